@@ -0,0 +1,291 @@
+package lc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"trpc.group/trpc-go/trpc-go/log"
+)
+
+// accessTraceMagic 访问记录文件头
+const accessTraceMagic = "LCTRACE\x01"
+
+// accessRecordSize 每条访问记录的字节数: 8字节key hash + 8字节时间 + 4字节数据大小 + 1字节操作 + 3字节填充
+const accessRecordSize = 24
+
+// ErrInvalidAccessTrace 访问记录文件格式错误
+var ErrInvalidAccessTrace = errors.New("lc: invalid access trace")
+
+// AccessOp 访问记录的操作类型
+type AccessOp uint8
+
+// 访问记录的操作类型定义
+const (
+	AccessGetHit  AccessOp = iota + 1 // 读取命中，Size为数据大小
+	AccessGetMiss                     // 读取未命中
+	AccessSet                         // 写入，Size为数据大小
+	AccessDelete                      // 删除
+	AccessRequest                     // 未开启cache的请求，Size为回包大小，用于评估开启cache后的命中率
+)
+
+// AccessTraceConfig 访问记录配置，按key hash采样，被采样的key记录所有访问
+type AccessTraceConfig struct {
+	// Path 记录文件路径，Writer为nil时使用
+	Path string `yaml:"path"`
+	// Writer 记录写入的位置，优先于Path
+	Writer io.Writer `yaml:"-"`
+	// SampleRate key的采样比例，取值(0, 1]，默认0.01
+	SampleRate float64 `yaml:"sample_rate"`
+	// BufferSize 待写入记录的缓冲条数，写入跟不上时丢弃，默认4096
+	BufferSize int `yaml:"buffer_size"`
+	// FlushInterval 刷新到文件的周期，默认1秒
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// AccessRecord 一条访问记录
+type AccessRecord struct {
+	// KeyHash key的fnv64a hash，不记录原始key
+	KeyHash uint64
+	// Time 访问时间，unix纳秒
+	Time int64
+	// Size 数据大小，未知时为0
+	Size int
+	// Op 操作类型
+	Op AccessOp
+}
+
+// AccessRecorder 采样记录访问，异步写入，Record不阻塞请求
+type AccessRecorder struct {
+	clock     Clock
+	threshold uint64 // 打散后的key hash不大于该值时采样
+	ch        chan AccessRecord
+	w         *bufio.Writer
+	closer    io.Closer
+	interval  time.Duration
+	drops     int64
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewAccessRecorder 创建访问记录器，用于在cache之外记录访问，如rpc_cache未开启cache的接口
+func NewAccessRecorder(cfg AccessTraceConfig) (*AccessRecorder, error) {
+	return newAccessRecorder(cfg, systemClock{})
+}
+
+// newAccessRecorder 创建使用clock记录时间的访问记录器
+func newAccessRecorder(cfg AccessTraceConfig, clock Clock) (*AccessRecorder, error) {
+	if cfg.SampleRate <= 0 || cfg.SampleRate > 1 {
+		cfg.SampleRate = 0.01
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 4096
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	r := &AccessRecorder{
+		clock:     clock,
+		threshold: uint64(cfg.SampleRate * math.MaxUint64),
+		ch:        make(chan AccessRecord, cfg.BufferSize),
+		interval:  cfg.FlushInterval,
+		done:      make(chan struct{}),
+	}
+	if cfg.SampleRate == 1 {
+		r.threshold = math.MaxUint64
+	}
+	w := cfg.Writer
+	if w == nil {
+		f, err := os.Create(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		w, r.closer = f, f
+	}
+	r.w = bufio.NewWriter(w)
+	var header [16]byte
+	copy(header[:8], accessTraceMagic)
+	binary.LittleEndian.PutUint64(header[8:], math.Float64bits(cfg.SampleRate))
+	if _, err := r.w.Write(header[:]); err != nil {
+		return nil, err
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r, nil
+}
+
+// sample key是否被采样，返回key hash
+func (r *AccessRecorder) sample(key string) (uint64, bool) {
+	if r == nil {
+		return 0, false
+	}
+	hash := hashKey(key)
+	// fnv的低位与分片相关，打散后再采样
+	mixed := hash * 0x9e3779b97f4a7c15
+	return hash, mixed <= r.threshold
+}
+
+// record 记录被采样key的一次访问，缓冲区满时丢弃
+func (r *AccessRecorder) record(hash uint64, op AccessOp, size int) {
+	select {
+	case r.ch <- AccessRecord{KeyHash: hash, Time: r.clock.Now().UnixNano(), Size: size, Op: op}:
+	default:
+		atomic.AddInt64(&r.drops, 1)
+	}
+}
+
+// Record 记录key的一次访问，key未被采样时忽略，r为nil时忽略
+func (r *AccessRecorder) Record(op AccessOp, key string, size int) {
+	if hash, ok := r.sample(key); ok {
+		r.record(hash, op, size)
+	}
+}
+
+// Sampled key是否被采样，用于只在需要时计算数据大小，r为nil时返回false
+func (r *AccessRecorder) Sampled(key string) bool {
+	_, ok := r.sample(key)
+	return ok
+}
+
+// Drops 因缓冲区满丢弃的记录数
+func (r *AccessRecorder) Drops() int64 {
+	if r == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&r.drops)
+}
+
+// Close 写入缓冲的记录后关闭，Path创建的文件同时关闭
+func (r *AccessRecorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+		err = r.w.Flush()
+		if r.closer != nil {
+			if cerr := r.closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+	})
+	return err
+}
+
+// run 写入记录并定期刷新
+func (r *AccessRecorder) run() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	var buf [accessRecordSize]byte
+	write := func(rec AccessRecord) {
+		binary.LittleEndian.PutUint64(buf[0:8], rec.KeyHash)
+		binary.LittleEndian.PutUint64(buf[8:16], uint64(rec.Time))
+		binary.LittleEndian.PutUint32(buf[16:20], uint32(rec.Size))
+		buf[20] = byte(rec.Op)
+		if _, err := r.w.Write(buf[:]); err != nil {
+			log.Errorf("lc: write trace err: %v", err)
+		}
+	}
+	for {
+		select {
+		case rec := <-r.ch:
+			write(rec)
+		case <-ticker.C:
+			if err := r.w.Flush(); err != nil {
+				log.Errorf("lc: flush trace err: %v", err)
+			}
+		case <-r.done:
+			for {
+				select {
+				case rec := <-r.ch:
+					write(rec)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// AccessTraceReader 读取访问记录
+type AccessTraceReader struct {
+	r          *bufio.Reader
+	sampleRate float64
+	buf        [accessRecordSize]byte
+}
+
+// NewAccessTraceReader 读取文件头，格式错误返回ErrInvalidAccessTrace
+func NewAccessTraceReader(r io.Reader) (*AccessTraceReader, error) {
+	tr := &AccessTraceReader{r: bufio.NewReader(r)}
+	var header [16]byte
+	if _, err := io.ReadFull(tr.r, header[:]); err != nil || string(header[:8]) != accessTraceMagic {
+		return nil, ErrInvalidAccessTrace
+	}
+	tr.sampleRate = math.Float64frombits(binary.LittleEndian.Uint64(header[8:]))
+	return tr, nil
+}
+
+// SampleRate 记录时的采样比例
+func (tr *AccessTraceReader) SampleRate() float64 {
+	return tr.sampleRate
+}
+
+// Next 读取下一条记录，没有更多记录时返回io.EOF
+func (tr *AccessTraceReader) Next() (AccessRecord, error) {
+	if _, err := io.ReadFull(tr.r, tr.buf[:]); err != nil {
+		if err == io.EOF {
+			return AccessRecord{}, io.EOF
+		}
+		return AccessRecord{}, ErrInvalidAccessTrace
+	}
+	return AccessRecord{
+		KeyHash: binary.LittleEndian.Uint64(tr.buf[0:8]),
+		Time:    int64(binary.LittleEndian.Uint64(tr.buf[8:16])),
+		Size:    int(binary.LittleEndian.Uint32(tr.buf[16:20])),
+		Op:      AccessOp(tr.buf[20]),
+	}, nil
+}
+
+// traceRead 记录一次读取，err为nil时记录命中及数据大小，对象模式不估算大小，size为0
+func (c *Cache) traceRead(key string, size int, err error) {
+	hash, ok := c.accessTrace.sample(key)
+	if !ok {
+		return
+	}
+	if err != nil {
+		c.accessTrace.record(hash, AccessGetMiss, 0)
+		return
+	}
+	c.accessTrace.record(hash, AccessGetHit, size)
+}
+
+// traceSet 记录一次写入，size为序列化后的数据大小
+func (c *Cache) traceSet(key string, size int) {
+	if hash, ok := c.accessTrace.sample(key); ok {
+		c.accessTrace.record(hash, AccessSet, size)
+	}
+}
+
+// traceObjectSet 对象模式记录一次写入，只对被采样的key估算对象大小
+func (c *Cache) traceObjectSet(key string, val interface{}) {
+	if hash, ok := c.accessTrace.sample(key); ok {
+		c.accessTrace.record(hash, AccessSet, objectSize(val))
+	}
+}
+
+// traceDelete 记录一次删除
+func (c *Cache) traceDelete(key string) {
+	if hash, ok := c.accessTrace.sample(key); ok {
+		c.accessTrace.record(hash, AccessDelete, 0)
+	}
+}
@@ -0,0 +1,96 @@
+package lc
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+
+	"github.com/trpc-extend/lc/lctest"
+)
+
+// readAccessTrace 读取所有访问记录
+func readAccessTrace(data []byte) (float64, []AccessRecord, error) {
+	tr, err := NewAccessTraceReader(bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, err
+	}
+	var records []AccessRecord
+	for {
+		rec, err := tr.Next()
+		if err == io.EOF {
+			return tr.SampleRate(), records, nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// TestAccessTrace 测试访问记录
+func TestAccessTrace(t *testing.T) {
+	convey.Convey("TestAccessTrace", t, func() {
+		for _, opt := range []Option{WithEvictionPolicy(EvictionFIFO), WithEvictionPolicy(EvictionLRU),
+			WithObjectMode(100)} {
+			var buf bytes.Buffer
+			clock := lctest.NewFakeClock(time.Time{})
+			cache := createCache("test-access-trace", WithShards(4), WithHardMaxCacheSize(16), WithClock(clock),
+				WithAccessTrace(AccessTraceConfig{Writer: &buf, SampleRate: 1}), opt)
+			var v []byte
+			convey.So(cache.Get("key", &v), convey.ShouldEqual, ErrRecordNotFound)
+			clock.Advance(time.Second)
+			convey.So(cache.Set("key", []byte("value")), convey.ShouldBeNil)
+			convey.So(cache.Get("key", &v), convey.ShouldBeNil)
+			convey.So(cache.Delete("key"), convey.ShouldBeNil)
+			convey.So(cache.Close(), convey.ShouldBeNil)
+
+			rate, records, err := readAccessTrace(buf.Bytes())
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(rate, convey.ShouldEqual, 1)
+			convey.So(len(records), convey.ShouldEqual, 4)
+			hash := hashKey("key")
+			ops := []AccessOp{AccessGetMiss, AccessSet, AccessGetHit, AccessDelete}
+			for i, rec := range records {
+				convey.So(rec.KeyHash, convey.ShouldEqual, hash)
+				convey.So(rec.Op, convey.ShouldEqual, ops[i])
+			}
+			convey.So(records[0].Time, convey.ShouldEqual, clock.Now().Add(-time.Second).UnixNano())
+			convey.So(records[1].Time, convey.ShouldEqual, clock.Now().UnixNano())
+			convey.So(records[1].Size, convey.ShouldEqual, len("value"))
+		}
+
+		// 按key采样，被采样的key记录所有访问
+		var buf bytes.Buffer
+		r, err := NewAccessRecorder(AccessTraceConfig{Writer: &buf, SampleRate: 0.1, BufferSize: 1 << 16})
+		convey.So(err, convey.ShouldBeNil)
+		sampled := 0
+		for i := 0; i < 10000; i++ {
+			key := "key:" + strconv.Itoa(i)
+			if r.Sampled(key) {
+				sampled++
+			}
+			r.Record(AccessRequest, key, 10)
+			r.Record(AccessRequest, key, 10)
+		}
+		convey.So(r.Close(), convey.ShouldBeNil)
+		convey.So(r.Drops(), convey.ShouldEqual, 0)
+		convey.So(sampled, convey.ShouldBeBetween, 800, 1200)
+		rate, records, err := readAccessTrace(buf.Bytes())
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(rate, convey.ShouldEqual, 0.1)
+		convey.So(len(records), convey.ShouldEqual, 2*sampled)
+
+		// nil记录器忽略访问，格式错误的文件返回ErrInvalidAccessTrace
+		var nilRecorder *AccessRecorder
+		nilRecorder.Record(AccessRequest, "key", 1)
+		convey.So(nilRecorder.Close(), convey.ShouldBeNil)
+		_, err = NewAccessTraceReader(bytes.NewReader([]byte("invalid trace header")))
+		convey.So(err, convey.ShouldEqual, ErrInvalidAccessTrace)
+		_, _, err = readAccessTrace(append(buf.Bytes()[:16:16], 1, 2, 3))
+		convey.So(err, convey.ShouldEqual, ErrInvalidAccessTrace)
+	})
+}
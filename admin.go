@@ -0,0 +1,50 @@
+package lc
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"trpc.group/trpc-go/trpc-go/admin"
+)
+
+// adminErrCode admin命令失败时的errorcode，与trpc admin一致
+const adminErrCode = 1
+
+func init() {
+	admin.HandleFunc("/cmds/lc/hotkeys", hotKeysHandler)
+}
+
+// hotKeysHandler admin命令，返回已注册cache的热点key
+// 参数name指定cache名称，不指定返回所有开启热点key统计的cache；参数n指定返回个数，默认10
+// 与定期上报一致，未开启ReportRawKeys时返回key的hash
+func hotKeysHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	n := hotKeysReportTopN
+	if s := r.URL.Query().Get("n"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			admin.ErrorOutput(w, "invalid n: "+s, adminErrCode)
+			return
+		}
+		n = v
+	}
+	name := r.URL.Query().Get("name")
+	caches := make(map[string][]HotKey)
+	cachePool.RLock()
+	for cacheName, cache := range cachePool.m {
+		if (name == "" || name == cacheName) && cache.hotKeys != nil {
+			caches[cacheName] = cache.exportHotKeys(n)
+		}
+	}
+	cachePool.RUnlock()
+	if name != "" && len(caches) == 0 {
+		admin.ErrorOutput(w, "cache not found or hot keys disabled: "+name, adminErrCode)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"errorcode": 0,
+		"message":   "",
+		"caches":    caches,
+	})
+}
@@ -0,0 +1,140 @@
+package lc
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AdmissionConfig 写入准入配置，key在窗口内被读取足够次数后才允许写入cache，过滤只访问一次的数据
+type AdmissionConfig struct {
+	// MinRequests 窗口内最少读取次数，默认2，最大16
+	MinRequests int `yaml:"min_requests"`
+	// Window 统计窗口，窗口结束后清空计数，默认1分钟
+	Window time.Duration `yaml:"window"`
+	// Counters 预估窗口内的key数量，决定sketch大小，默认使用MaxEntriesInWindow
+	Counters int `yaml:"counters"`
+}
+
+// AdmissionStats 写入准入统计
+type AdmissionStats struct {
+	// Admits 允许写入数
+	Admits int64 `json:"admits"`
+	// Rejects 拒绝写入数
+	Rejects int64 `json:"rejects"`
+}
+
+// admission 写入准入过滤器，按key hash分段，每段有独立的锁、doorkeeper和count-min sketch，
+// doorkeeper记录第一次读取，之后的读取记录在sketch中
+type admission struct {
+	stripes     []admissionStripe
+	shift       uint // 选择分段时hash右移的位数
+	minRequests int
+	window      time.Duration
+	clock       Clock
+	admits      int64
+	rejects     int64
+}
+
+// admissionStripe 准入过滤器的一个分段
+type admissionStripe struct {
+	mu          sync.Mutex
+	sketch      *cmSketch
+	door        *doorkeeper
+	windowStart time.Time
+}
+
+// newAdmission 创建准入过滤器，按stripes分段(向上取整为2的幂)，未设置的配置使用默认值
+func newAdmission(cfg AdmissionConfig, counters, stripes int, clock Clock) *admission {
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 2
+	}
+	if cfg.MinRequests > cmMaxCount+1 {
+		cfg.MinRequests = cmMaxCount + 1
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.Counters > 0 {
+		counters = cfg.Counters
+	}
+	stripes = nextPowerOfTwo(stripes)
+	counters /= stripes
+	if counters < 16 {
+		counters = 16
+	}
+	a := &admission{
+		stripes:     make([]admissionStripe, stripes),
+		shift:       uint(64 - bits.TrailingZeros(uint(stripes))),
+		minRequests: cfg.MinRequests,
+		window:      cfg.Window,
+		clock:       clock,
+	}
+	now := clock.Now()
+	for i := range a.stripes {
+		a.stripes[i] = admissionStripe{sketch: newCMSketch(counters), door: newDoorkeeper(counters * 8), windowStart: now}
+	}
+	return a
+}
+
+// stripe 选择hash所在的分段，使用打散后的高位，避免同一分段内hash低位相同影响doorkeeper的分布
+func (a *admission) stripe(hash uint64) *admissionStripe {
+	return &a.stripes[(hash*0xff51afd7ed558ccd)>>a.shift]
+}
+
+// rotate 窗口结束后清空分段的计数，调用方需持有分段的锁
+func (s *admissionStripe) rotate(now time.Time, window time.Duration) {
+	if now.Sub(s.windowStart) >= window {
+		s.sketch.reset()
+		s.door.reset()
+		s.windowStart = now
+	}
+}
+
+// record 记录一次读取
+func (a *admission) record(key string) {
+	if a == nil {
+		return
+	}
+	hash := hashKey(key)
+	s := a.stripe(hash)
+	now := a.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotate(now, a.window)
+	if s.door.add(hash) {
+		s.sketch.increment(hash)
+	}
+}
+
+// admit 判断key是否允许写入，写入本身不计入读取次数
+func (a *admission) admit(key string) bool {
+	if a == nil {
+		return true
+	}
+	hash := hashKey(key)
+	s := a.stripe(hash)
+	now := a.clock.Now()
+	s.mu.Lock()
+	s.rotate(now, a.window)
+	n := s.sketch.estimate(hash)
+	if s.door.contains(hash) {
+		n++
+	}
+	s.mu.Unlock()
+	if n < a.minRequests {
+		atomic.AddInt64(&a.rejects, 1)
+		return false
+	}
+	atomic.AddInt64(&a.admits, 1)
+	return true
+}
+
+// snapshot 返回准入统计
+func (a *admission) snapshot() AdmissionStats {
+	if a == nil {
+		return AdmissionStats{}
+	}
+	return AdmissionStats{Admits: atomic.LoadInt64(&a.admits), Rejects: atomic.LoadInt64(&a.rejects)}
+}
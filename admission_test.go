@@ -0,0 +1,80 @@
+package lc
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestAdmission 单测写入准入
+func TestAdmission(t *testing.T) {
+	convey.Convey("TestAdmission", t, func() {
+		cache := createCache("test-admission", WithAdmission(AdmissionConfig{MinRequests: 2, Window: time.Second}))
+		defer cache.Close()
+
+		// 只读取过一次的key不保存
+		loads := 0
+		load := func() (interface{}, error) {
+			loads++
+			v := "v"
+			return &v, nil
+		}
+		var v string
+		convey.So(cache.GetWithLoad(context.Background(), "key", &v, load), convey.ShouldBeNil)
+		convey.So(v, convey.ShouldEqual, "v")
+		convey.So(cache.Len(), convey.ShouldEqual, 0)
+		// 第二次读取后允许写入
+		convey.So(cache.GetWithLoad(context.Background(), "key", &v, load), convey.ShouldBeNil)
+		convey.So(cache.GetWithLoad(context.Background(), "key", &v, load), convey.ShouldBeNil)
+		convey.So(loads, convey.ShouldEqual, 2)
+		stats := cache.Stats().Admission
+		convey.So(stats.Admits, convey.ShouldEqual, 1)
+		convey.So(stats.Rejects, convey.ShouldEqual, 1)
+
+		// 窗口结束后计数清空，未通过准入的写入删除旧数据
+		time.Sleep(1100 * time.Millisecond)
+		convey.So(cache.Set("key", "new"), convey.ShouldBeNil)
+		_, err := cache.GetString("key")
+		convey.So(err, convey.ShouldEqual, ErrRecordNotFound)
+		_, err = cache.GetString("key")
+		convey.So(err, convey.ShouldEqual, ErrRecordNotFound)
+		convey.So(cache.Set("key", "new"), convey.ShouldBeNil)
+		s, err := cache.GetString("key")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(s, convey.ShouldEqual, "new")
+	})
+}
+
+// TestAdmissionStripes 单测分段的准入过滤器并发读取
+func TestAdmissionStripes(t *testing.T) {
+	convey.Convey("TestAdmissionStripes", t, func() {
+		a := newAdmission(AdmissionConfig{MinRequests: 2}, 1<<16, 64, systemClock{})
+		convey.So(len(a.stripes), convey.ShouldEqual, 64)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					a.record("key:" + strconv.Itoa(j))
+				}
+			}(i)
+		}
+		wg.Wait()
+		admitted, rejected := 0, 0
+		for j := 0; j < 1000; j++ {
+			if a.admit("key:" + strconv.Itoa(j)) {
+				admitted++
+			}
+			if !a.admit("other:" + strconv.Itoa(j)) {
+				rejected++
+			}
+		}
+		convey.So(admitted, convey.ShouldEqual, 1000)
+		convey.So(rejected, convey.ShouldBeGreaterThan, 990)
+	})
+}
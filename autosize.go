@@ -0,0 +1,184 @@
+package lc
+
+import (
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"trpc.group/trpc-go/trpc-go/log"
+)
+
+// AutoSizeConfig 根据容器内存限制自动设置cache大小
+type AutoSizeConfig struct {
+	// Fraction cache占容器内存限制的比例，默认0.25
+	Fraction float64 `yaml:"fraction"`
+	// ShrinkOnPressure 堆内存持续超过阈值时淘汰最早的数据
+	ShrinkOnPressure bool `yaml:"shrink_on_pressure"`
+	// PressureThreshold 堆内存占容器内存限制的比例阈值，默认0.85
+	PressureThreshold float64 `yaml:"pressure_threshold"`
+	// CheckInterval 检查堆内存的周期，默认10秒
+	CheckInterval time.Duration `yaml:"check_interval"`
+	// SustainedChecks 连续超过阈值的次数，达到后淘汰数据，默认3
+	SustainedChecks int `yaml:"sustained_checks"`
+	// ShrinkFraction 每次淘汰的数据比例，默认0.1
+	ShrinkFraction float64 `yaml:"shrink_fraction"`
+}
+
+// withDefaults 未设置的配置使用默认值
+func (a AutoSizeConfig) withDefaults() AutoSizeConfig {
+	if a.Fraction <= 0 || a.Fraction > 1 {
+		a.Fraction = 0.25
+	}
+	if a.PressureThreshold <= 0 || a.PressureThreshold > 1 {
+		a.PressureThreshold = 0.85
+	}
+	if a.CheckInterval <= 0 {
+		a.CheckInterval = 10 * time.Second
+	}
+	if a.SustainedChecks <= 0 {
+		a.SustainedChecks = 3
+	}
+	if a.ShrinkFraction <= 0 || a.ShrinkFraction > 1 {
+		a.ShrinkFraction = 0.1
+	}
+	return a
+}
+
+// memoryLimit 获取容器内存限制，单测中替换
+var memoryLimit = cgroupMemoryLimit
+
+// parseCgroupMemoryLimit 解析cgroup内存限制，v2的"max"和v1接近MaxInt64的值表示未限制
+func parseCgroupMemoryLimit(data string) (int64, bool) {
+	data = strings.TrimSpace(data)
+	if data == "" || data == "max" {
+		return 0, false
+	}
+	limit, err := strconv.ParseInt(data, 10, 64)
+	if err != nil || limit <= 0 || limit >= 1<<62 {
+		return 0, false
+	}
+	return limit, true
+}
+
+// cgroupMemoryLimitFilesOf 根据/proc/self/cgroup的内容返回进程所在cgroup及其所有上级cgroup的内存限制文件，
+// v2为memory.max，v1为memory控制器的memory.limit_in_bytes；容器使用cgroup namespace时路径为"/"，即容器自身的cgroup
+func cgroupMemoryLimitFilesOf(root, procCgroup string) []string {
+	var files []string
+	for _, line := range strings.Split(procCgroup, "\n") {
+		// 格式: hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		var dir, file string
+		switch {
+		case fields[0] == "0" && fields[1] == "":
+			dir, file = root, "memory.max"
+		case hasCgroupController(fields[1], "memory"):
+			dir, file = path.Join(root, "memory"), "memory.limit_in_bytes"
+		default:
+			continue
+		}
+		for p := path.Clean("/" + fields[2]); ; p = path.Dir(p) {
+			files = append(files, path.Join(dir, p, file))
+			if p == "/" {
+				break
+			}
+		}
+	}
+	return files
+}
+
+// hasCgroupController controllers是否包含controller
+func hasCgroupController(controllers, controller string) bool {
+	for _, c := range strings.Split(controllers, ",") {
+		if c == controller {
+			return true
+		}
+	}
+	return false
+}
+
+// autoSize 按容器内存限制设置HardMaxCacheSize，返回内存限制，未限制返回0
+func autoSize(name string, cfg *Config) int64 {
+	limit, ok := memoryLimit()
+	if !ok {
+		log.Warnf("lc: auto size disabled, no cgroup memory limit, name:%v", name)
+		return 0
+	}
+	a := cfg.AutoSize.withDefaults()
+	size := int(float64(limit) * a.Fraction / (1024 * 1024))
+	if size < 1 {
+		size = 1
+	}
+	log.Infof("lc: auto size, name:%v, memory limit:%vMB, hard max cache size:%vMB", name,
+		limit/(1024*1024), size)
+	cfg.HardMaxCacheSize = size
+	return limit
+}
+
+// watchHeapPressure 堆内存连续SustainedChecks次超过阈值时淘汰ShrinkFraction比例的最早数据
+func (c *Cache) watchHeapPressure(cfg AutoSizeConfig, limit int64) {
+	cfg = cfg.withDefaults()
+	threshold := uint64(float64(limit) * cfg.PressureThreshold)
+	ticker := time.NewTicker(cfg.CheckInterval)
+	defer ticker.Stop()
+	var ms runtime.MemStats
+	checks := 0
+	for {
+		select {
+		case <-ticker.C:
+			runtime.ReadMemStats(&ms)
+			if ms.HeapAlloc < threshold {
+				checks = 0
+				continue
+			}
+			if checks++; checks < cfg.SustainedChecks {
+				continue
+			}
+			checks = 0
+			log.Warnf("lc: heap pressure, shrink cache, name:%v, heap:%vMB, limit:%vMB, fraction:%v", c.name,
+				ms.HeapAlloc/(1024*1024), limit/(1024*1024), cfg.ShrinkFraction)
+			c.shrink(cfg.ShrinkFraction)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// shrink 淘汰fraction比例的数据
+// lru/tinylfu存储按淘汰策略淘汰并释放内存，对象模式淘汰最早写入的对象；bigcache按写入时间删除最早的数据，空间可被复用但不会归还给堆
+func (c *Cache) shrink(fraction float64) {
+	if ms, ok := c.st.(*memStore); ok {
+		ms.shrink(fraction)
+		return
+	}
+	if c.objects != nil {
+		c.objects.shrink(fraction)
+		return
+	}
+	type keyTime struct {
+		key       string
+		timestamp int64
+	}
+	var keys []keyTime
+	it := c.st.Iterator()
+	for it.SetNext() {
+		key, _, entry, err := it.Value()
+		if err != nil || isChunkKey(key) {
+			continue
+		}
+		if h, _, err := unwrapEntry(entry); err == nil {
+			keys = append(keys, keyTime{key: key, timestamp: h.timestamp})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].timestamp < keys[j].timestamp })
+	for _, k := range keys[:int(float64(len(keys))*fraction)] {
+		unlock := c.locks.lock(k.key)
+		_ = c.remove(k.key)
+		unlock()
+	}
+}
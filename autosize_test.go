@@ -0,0 +1,73 @@
+package lc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestAutoSize 单测根据容器内存限制自动设置cache大小
+func TestAutoSize(t *testing.T) {
+	convey.Convey("TestAutoSize", t, func() {
+		convey.Convey("parse cgroup memory limit", func() {
+			limit, ok := parseCgroupMemoryLimit("2147483648\n")
+			convey.So(ok, convey.ShouldBeTrue)
+			convey.So(limit, convey.ShouldEqual, 2<<30)
+			_, ok = parseCgroupMemoryLimit("max\n")
+			convey.So(ok, convey.ShouldBeFalse)
+			_, ok = parseCgroupMemoryLimit("9223372036854771712")
+			convey.So(ok, convey.ShouldBeFalse)
+			_, ok = parseCgroupMemoryLimit("")
+			convey.So(ok, convey.ShouldBeFalse)
+		})
+		convey.Convey("cgroup memory limit files", func() {
+			files := cgroupMemoryLimitFilesOf("/sys/fs/cgroup", "0::/kubepods/pod1/app\n")
+			convey.So(files, convey.ShouldResemble, []string{
+				"/sys/fs/cgroup/kubepods/pod1/app/memory.max",
+				"/sys/fs/cgroup/kubepods/pod1/memory.max",
+				"/sys/fs/cgroup/kubepods/memory.max",
+				"/sys/fs/cgroup/memory.max",
+			})
+			files = cgroupMemoryLimitFilesOf("/sys/fs/cgroup", "12:cpu,cpuacct:/docker/abc\n4:memory:/docker/abc\n0::/\n")
+			convey.So(files, convey.ShouldResemble, []string{
+				"/sys/fs/cgroup/memory/docker/abc/memory.limit_in_bytes",
+				"/sys/fs/cgroup/memory/docker/memory.limit_in_bytes",
+				"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+				"/sys/fs/cgroup/memory.max",
+			})
+			convey.So(cgroupMemoryLimitFilesOf("/sys/fs/cgroup", ""), convey.ShouldBeEmpty)
+		})
+		convey.Convey("hard max cache size", func() {
+			defer func(f func() (int64, bool)) { memoryLimit = f }(memoryLimit)
+			memoryLimit = func() (int64, bool) { return 4 << 30, true }
+			cache := createCache("test-autosize", WithEvictionPolicy(EvictionLRU),
+				WithAutoSize(AutoSizeConfig{Fraction: 0.5}))
+			defer cache.Close()
+			convey.So(cache.st.(*memStore).maxCost(), convey.ShouldEqual, 2<<30)
+
+			memoryLimit = func() (int64, bool) { return 0, false }
+			cache = createCache("test-autosize", WithEvictionPolicy(EvictionLRU), WithHardMaxCacheSize(1),
+				WithAutoSize(AutoSizeConfig{}))
+			defer cache.Close()
+			convey.So(cache.st.(*memStore).maxCost(), convey.ShouldEqual, 1<<20)
+		})
+		convey.Convey("shrink", func() {
+			for _, policy := range []EvictionPolicy{EvictionFIFO, EvictionLRU, EvictionTinyLFU} {
+				cache := createCache("test-shrink", WithShards(1), WithEvictionPolicy(policy))
+				for i := 0; i < 100; i++ {
+					convey.So(cache.SetInt64(fmt.Sprintf("k%02d", i), int64(i)), convey.ShouldBeNil)
+				}
+				cache.shrink(0.2)
+				convey.So(cache.Len(), convey.ShouldBeBetweenOrEqual, 79, 81)
+				if policy != EvictionTinyLFU {
+					_, err := cache.GetInt64("k00")
+					convey.So(err, convey.ShouldEqual, ErrRecordNotFound)
+					_, err = cache.GetInt64("k99")
+					convey.So(err, convey.ShouldBeNil)
+				}
+				cache.Close()
+			}
+		})
+	})
+}
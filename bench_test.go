@@ -0,0 +1,273 @@
+package lc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"testing"
+)
+
+// benchKeys 压测使用的key个数
+const benchKeys = 1 << 14
+
+// benchCache 创建压测使用的cache并写入benchKeys个key，policy为"object"时使用对象模式
+func benchCache(b *testing.B, policy string) (*Cache, []string) {
+	opts := []Option{WithShards(64), WithHardMaxCacheSize(256), WithMaxEntriesInWindow(benchKeys)}
+	if policy == "object" {
+		opts = append(opts, WithObjectMode(benchKeys))
+	} else {
+		opts = append(opts, WithEvictionPolicy(EvictionPolicy(policy)))
+	}
+	cache := createCache("bench-"+policy, opts...)
+	b.Cleanup(func() { _ = cache.Close() })
+	keys := make([]string, benchKeys)
+	value := bytes.Repeat([]byte("v"), 256)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+		if err := cache.Set(keys[i], value); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return cache, keys
+}
+
+// benchPolicies 对每种存储执行fn
+func benchPolicies(b *testing.B, fn func(b *testing.B, cache *Cache, keys []string)) {
+	for _, policy := range []string{string(EvictionFIFO), string(EvictionLRU), string(EvictionTinyLFU), "object"} {
+		b.Run(policy, func(b *testing.B) {
+			cache, keys := benchCache(b, policy)
+			b.ReportAllocs()
+			b.ResetTimer()
+			fn(b, cache, keys)
+		})
+	}
+}
+
+// benchBytePolicies 对序列化存储执行fn，对象模式不支持操作序列化数据的接口
+func benchBytePolicies(b *testing.B, fn func(b *testing.B, cache *Cache, keys []string)) {
+	for _, policy := range []string{string(EvictionFIFO), string(EvictionLRU), string(EvictionTinyLFU)} {
+		b.Run(policy, func(b *testing.B) {
+			cache, keys := benchCache(b, policy)
+			b.ReportAllocs()
+			b.ResetTimer()
+			fn(b, cache, keys)
+		})
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	benchPolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		var v []byte
+		for i := 0; i < b.N; i++ {
+			_ = cache.Get(keys[i&(benchKeys-1)], &v)
+		}
+	})
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	benchPolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		b.RunParallel(func(pb *testing.PB) {
+			var v []byte
+			for i := 0; pb.Next(); i++ {
+				_ = cache.Get(keys[i&(benchKeys-1)], &v)
+			}
+		})
+	})
+}
+
+func BenchmarkSet(b *testing.B) {
+	value := bytes.Repeat([]byte("v"), 256)
+	benchPolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			_ = cache.Set(keys[i&(benchKeys-1)], value)
+		}
+	})
+}
+
+func BenchmarkSetParallel(b *testing.B) {
+	value := bytes.Repeat([]byte("v"), 256)
+	benchPolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				_ = cache.Set(keys[i&(benchKeys-1)], value)
+			}
+		})
+	})
+}
+
+func BenchmarkGetWithEntryStatus(b *testing.B) {
+	benchPolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		var v []byte
+		for i := 0; i < b.N; i++ {
+			_, _ = cache.GetWithEntryStatus(keys[i&(benchKeys-1)], &v)
+		}
+	})
+}
+
+func BenchmarkGetWithLoadHit(b *testing.B) {
+	load := func() (interface{}, error) { return &[]byte{}, nil }
+	benchPolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		var v []byte
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			_ = cache.GetWithLoad(ctx, keys[i&(benchKeys-1)], &v, load)
+		}
+	})
+}
+
+func BenchmarkGetWithLoadMiss(b *testing.B) {
+	value := bytes.Repeat([]byte("v"), 256)
+	load := func() (interface{}, error) { return &value, nil }
+	benchPolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		var v []byte
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			key := keys[i&(benchKeys-1)]
+			_ = cache.Delete(key)
+			_ = cache.GetWithLoad(ctx, key, &v, load)
+		}
+	})
+}
+
+func BenchmarkGetBytes(b *testing.B) {
+	benchBytePolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			_, _ = cache.GetBytes(keys[i&(benchKeys-1)])
+		}
+	})
+}
+
+func BenchmarkGetInto(b *testing.B) {
+	benchBytePolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		buf := make([]byte, 0, 512)
+		for i := 0; i < b.N; i++ {
+			buf, _ = cache.GetInto(keys[i&(benchKeys-1)], buf[:0])
+		}
+	})
+}
+
+func BenchmarkView(b *testing.B) {
+	benchBytePolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		n := 0
+		fn := func(data []byte) error {
+			n += len(data)
+			return nil
+		}
+		for i := 0; i < b.N; i++ {
+			_ = cache.View(keys[i&(benchKeys-1)], fn)
+		}
+	})
+}
+
+func BenchmarkDelete(b *testing.B) {
+	value := bytes.Repeat([]byte("v"), 256)
+	benchPolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			key := keys[i&(benchKeys-1)]
+			_ = cache.Delete(key)
+			b.StopTimer()
+			_ = cache.Set(key, value)
+			b.StartTimer()
+		}
+	})
+}
+
+func BenchmarkTouch(b *testing.B) {
+	benchPolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			_ = cache.Touch(keys[i&(benchKeys-1)])
+		}
+	})
+}
+
+func BenchmarkTTL(b *testing.B) {
+	benchPolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			_, _ = cache.TTL(keys[i&(benchKeys-1)])
+		}
+	})
+}
+
+func BenchmarkIncr(b *testing.B) {
+	benchBytePolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			_, _ = cache.Incr("counter:"+strconv.Itoa(i&15), 1)
+		}
+	})
+}
+
+func BenchmarkCompareAndSwap(b *testing.B) {
+	benchBytePolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		_ = cache.SetInt64("cas", 0)
+		for i := 0; i < b.N; i++ {
+			_, _ = cache.CompareAndSwap("cas", int64(i), int64(i+1))
+		}
+	})
+}
+
+func BenchmarkSetNX(b *testing.B) {
+	benchBytePolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			_, _ = cache.SetNX(keys[i&(benchKeys-1)], "v")
+		}
+	})
+}
+
+func BenchmarkSetGetString(b *testing.B) {
+	benchBytePolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			key := keys[i&(benchKeys-1)]
+			_ = cache.SetString(key, "value")
+			_, _ = cache.GetString(key)
+		}
+	})
+}
+
+func BenchmarkKeyStats(b *testing.B) {
+	benchBytePolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			_, _ = cache.KeyStats(keys[i&(benchKeys-1)])
+		}
+	})
+}
+
+func BenchmarkScan(b *testing.B) {
+	benchBytePolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			_, _ = cache.Scan("key:1", func(string, []byte) bool { return true })
+		}
+	})
+}
+
+func BenchmarkKeys(b *testing.B) {
+	benchPolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			_, _ = cache.Keys("key:1")
+		}
+	})
+}
+
+func BenchmarkExportJSONL(b *testing.B) {
+	benchBytePolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			_, _ = cache.ExportJSONL(io.Discard, nil)
+		}
+	})
+}
+
+func BenchmarkWriteSnapshot(b *testing.B) {
+	benchBytePolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			_, _ = cache.WriteSnapshot(io.Discard)
+		}
+	})
+}
+
+func BenchmarkStats(b *testing.B) {
+	benchPolicies(b, func(b *testing.B, cache *Cache, keys []string) {
+		for i := 0; i < b.N; i++ {
+			_ = cache.Stats()
+		}
+	})
+}
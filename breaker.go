@@ -0,0 +1,191 @@
+package lc
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState 熔断器状态
+type BreakerState int32
+
+// 熔断器状态定义
+const (
+	BreakerClosed   BreakerState = iota // 关闭，正常调用穿透函数
+	BreakerOpen                         // 打开，不调用穿透函数
+	BreakerHalfOpen                     // 半开，允许少量探测请求
+)
+
+// String 状态名称
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig 穿透函数熔断配置
+type BreakerConfig struct {
+	// Window 错误率统计窗口，默认10秒
+	Window time.Duration `yaml:"window"`
+	// MinRequests 窗口内最少请求数，低于该值不触发熔断，默认20
+	MinRequests int `yaml:"min_requests"`
+	// ErrorRate 触发熔断的错误率，取值(0, 1]，默认0.5
+	ErrorRate float64 `yaml:"error_rate"`
+	// OpenTimeout 熔断打开后进入半开状态的时间，默认5秒
+	OpenTimeout time.Duration `yaml:"open_timeout"`
+	// HalfOpenProbes 半开状态的探测请求数，全部成功后关闭熔断，默认1
+	HalfOpenProbes int `yaml:"half_open_probes"`
+}
+
+// BreakerStats 熔断器统计
+type BreakerStats struct {
+	// State 当前状态
+	State BreakerState `json:"state"`
+	// Requests 当前窗口内的请求数
+	Requests int64 `json:"requests"`
+	// Failures 当前窗口内的失败数
+	Failures int64 `json:"failures"`
+	// Opens 熔断打开次数
+	Opens int64 `json:"opens"`
+	// Rejects 熔断拒绝的请求数
+	Rejects int64 `json:"rejects"`
+}
+
+// breaker 穿透函数熔断器
+type breaker struct {
+	mu          sync.Mutex
+	cfg         BreakerConfig
+	clock       Clock
+	state       BreakerState
+	windowStart time.Time
+	openedAt    time.Time
+	probes      int // 半开状态已放行的探测数
+	successes   int // 半开状态探测成功数
+	stats       BreakerStats
+}
+
+// newBreaker 创建熔断器，未设置的配置使用默认值
+func newBreaker(cfg BreakerConfig, clock Clock) *breaker {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.ErrorRate <= 0 || cfg.ErrorRate > 1 {
+		cfg.ErrorRate = 0.5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 5 * time.Second
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	return &breaker{cfg: cfg, clock: clock, windowStart: clock.Now()}
+}
+
+// allow 判断是否允许调用穿透函数
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.cfg.OpenTimeout {
+			b.stats.Rejects++
+			return false
+		}
+		b.state, b.probes, b.successes = BreakerHalfOpen, 0, 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probes >= b.cfg.HalfOpenProbes {
+			b.stats.Rejects++
+			return false
+		}
+		b.probes++
+		return true
+	default:
+		if now.Sub(b.windowStart) >= b.cfg.Window {
+			b.resetWindow(now)
+		}
+		return true
+	}
+}
+
+// done 记录穿透函数调用结果
+func (b *breaker) done(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	switch b.state {
+	case BreakerHalfOpen:
+		if err != nil {
+			b.open(now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenProbes {
+			b.state = BreakerClosed
+			b.resetWindow(now)
+		}
+	case BreakerClosed:
+		b.stats.Requests++
+		if err != nil {
+			b.stats.Failures++
+		}
+		if b.stats.Requests >= int64(b.cfg.MinRequests) &&
+			float64(b.stats.Failures)/float64(b.stats.Requests) >= b.cfg.ErrorRate {
+			b.open(now)
+		}
+	}
+}
+
+// cancel allow通过后未调用穿透函数，归还半开状态的探测名额
+func (b *breaker) cancel() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// open 打开熔断
+func (b *breaker) open(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.stats.Opens++
+	b.resetWindow(now)
+}
+
+// resetWindow 重置统计窗口
+func (b *breaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.stats.Requests, b.stats.Failures = 0, 0
+}
+
+// snapshot 返回熔断器统计
+func (b *breaker) snapshot() BreakerStats {
+	if b == nil {
+		return BreakerStats{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := b.stats
+	stats.State = b.state
+	return stats
+}
@@ -0,0 +1,109 @@
+package lc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+
+	"trpc.group/trpc-go/trpc-go/codec"
+
+	"github.com/trpc-extend/lc/lctest"
+)
+
+// TestCircuitBreaker 单测穿透函数熔断
+func TestCircuitBreaker(t *testing.T) {
+	convey.Convey("TestCircuitBreaker", t, func() {
+		cache := createCache("test-breaker", WithCircuitBreaker(BreakerConfig{
+			MinRequests: 2, ErrorRate: 0.5, OpenTimeout: 100 * time.Millisecond}))
+		defer cache.Close()
+
+		calls := 0
+		failFunc := func() (interface{}, error) {
+			calls++
+			return nil, errors.New("load fail")
+		}
+		getData := &TestParam{}
+		for i := 0; i < 2; i++ {
+			err := cache.GetWithLoad(context.TODO(), "key", getData, failFunc, codec.SerializationTypeJSON)
+			convey.So(err, convey.ShouldNotBeNil)
+		}
+		convey.So(cache.Stats().Breaker.State, convey.ShouldEqual, BreakerOpen)
+
+		// 熔断打开，不调用穿透函数
+		err := cache.GetWithLoad(context.TODO(), "key", getData, failFunc, codec.SerializationTypeJSON)
+		convey.So(err, convey.ShouldEqual, ErrCircuitOpen)
+		convey.So(calls, convey.ShouldEqual, 2)
+		convey.So(cache.Stats().Breaker.Rejects, convey.ShouldEqual, 1)
+
+		// 半开探测成功后恢复
+		time.Sleep(150 * time.Millisecond)
+		err = cache.GetWithLoad(context.TODO(), "key", getData, func() (interface{}, error) {
+			return &TestParam{Name: "probe"}, nil
+		}, codec.SerializationTypeJSON)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(getData.Name, convey.ShouldEqual, "probe")
+		stats := cache.Stats().Breaker
+		convey.So(stats.State, convey.ShouldEqual, BreakerClosed)
+		convey.So(stats.Opens, convey.ShouldEqual, 1)
+
+		// 半开探测未调用穿透函数时归还名额
+		b := newBreaker(BreakerConfig{MinRequests: 1, ErrorRate: 1, HalfOpenProbes: 1}, systemClock{})
+		b.done(errors.New("load fail"))
+		b.openedAt = time.Now().Add(-time.Hour)
+		convey.So(b.allow(), convey.ShouldBeTrue)
+		b.cancel()
+		convey.So(b.allow(), convey.ShouldBeTrue)
+		convey.So(b.allow(), convey.ShouldBeFalse)
+	})
+}
+
+// TestLoadTimeout 单测穿透函数超时计为熔断失败并使用过期数据兜底
+func TestLoadTimeout(t *testing.T) {
+	convey.Convey("TestLoadTimeout", t, func() {
+		clock := lctest.NewFakeClock(time.Time{})
+		cache := createCache("test-load-timeout", WithLoadTimeout(20*time.Millisecond), WithClock(clock),
+			WithLifeWindow(time.Minute), WithCleanWindow(0), WithAllowUseExpiredEntry(true),
+			WithCircuitBreaker(BreakerConfig{Window: time.Hour, MinRequests: 2, ErrorRate: 0.5, OpenTimeout: time.Minute}))
+		defer cache.Close()
+
+		release := make(chan struct{})
+		defer close(release)
+		slowFunc := func() (interface{}, error) {
+			<-release
+			return &TestParam{Name: "slow"}, nil
+		}
+		getData := &TestParam{}
+		begin := time.Now()
+		err := cache.GetWithLoad(context.TODO(), "key", getData, slowFunc, codec.SerializationTypeJSON)
+		convey.So(err, convey.ShouldEqual, ErrLoadTimeout)
+		convey.So(time.Since(begin), convey.ShouldBeLessThan, time.Second)
+
+		// 超时后使用过期数据兜底
+		convey.So(cache.Set("expired", &TestParam{Name: "expired"}, codec.SerializationTypeJSON), convey.ShouldBeNil)
+		clock.Advance(time.Minute)
+		err = cache.GetWithLoad(context.TODO(), "expired", getData, slowFunc, codec.SerializationTypeJSON)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(getData.Name, convey.ShouldEqual, "expired")
+
+		// 超时计为熔断失败
+		stats := cache.Stats().Breaker
+		convey.So(stats.State, convey.ShouldEqual, BreakerOpen)
+		convey.So(stats.Opens, convey.ShouldEqual, 1)
+		convey.So(cache.HitStats(), convey.ShouldResemble, cache.Stats().Stats)
+
+		// GetWithLoadContext超时后取消穿透函数的ctx
+		ctxCache := createCache("test-load-timeout-ctx", WithLoadTimeout(20*time.Millisecond))
+		defer ctxCache.Close()
+		canceled := make(chan struct{})
+		err = ctxCache.GetWithLoadContext(context.TODO(), "key", getData, func(ctx context.Context) (interface{}, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		}, codec.SerializationTypeJSON)
+		convey.So(err, convey.ShouldEqual, ErrLoadTimeout)
+		<-canceled
+	})
+}
@@ -0,0 +1,28 @@
+package lc
+
+import "sync"
+
+// maxPooledBufSize 超过该大小的缓冲区不放回池中，避免长期占用大块内存
+const maxPooledBufSize = 1024 * 1024
+
+// entryBufPool 写入数据时复用的缓冲区，存储会拷贝写入的数据，写入后即可复用
+var entryBufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// getEntryBuf 获取缓冲区
+func getEntryBuf() *[]byte {
+	return entryBufPool.Get().(*[]byte)
+}
+
+// putEntryBuf 归还缓冲区
+func putEntryBuf(b *[]byte) {
+	if cap(*b) > maxPooledBufSize {
+		return
+	}
+	*b = (*b)[:0]
+	entryBufPool.Put(b)
+}
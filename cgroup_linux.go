@@ -0,0 +1,38 @@
+//go:build linux
+
+package lc
+
+import "os"
+
+const (
+	// cgroupRoot cgroup挂载目录
+	cgroupRoot = "/sys/fs/cgroup"
+	// procSelfCgroup 进程所在的cgroup
+	procSelfCgroup = "/proc/self/cgroup"
+)
+
+// cgroup根目录的内存限制文件, 无法读取procSelfCgroup时依次尝试v2和v1
+var cgroupMemoryLimitFiles = []string{
+	cgroupRoot + "/memory.max",
+	cgroupRoot + "/memory/memory.limit_in_bytes",
+}
+
+// cgroupMemoryLimit 读取进程所在cgroup的内存限制，上级cgroup的限制同样生效，取最小值，未限制返回false
+func cgroupMemoryLimit() (int64, bool) {
+	files := cgroupMemoryLimitFiles
+	if data, err := os.ReadFile(procSelfCgroup); err == nil {
+		files = cgroupMemoryLimitFilesOf(cgroupRoot, string(data))
+	}
+	var limit int64
+	found := false
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if n, ok := parseCgroupMemoryLimit(string(data)); ok && (!found || n < limit) {
+			limit, found = n, true
+		}
+	}
+	return limit, found
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package lc
+
+// cgroupMemoryLimit 非linux系统没有cgroup，始终返回false
+func cgroupMemoryLimit() (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,142 @@
+package lc
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"github.com/allegro/bigcache/v3"
+)
+
+// flagChunked 头部标志位，数据被分块保存，数据内容为分块清单
+const flagChunked byte = 1 << 0
+
+// chunkKeyPrefix 分块key的前缀，以\x00开头避免与业务key冲突
+const chunkKeyPrefix = "\x00lc:chunk:"
+
+// chunkManifestSize 分块清单长度: 4字节分块数 + 8字节数据总长度
+const chunkManifestSize = 12
+
+// chunkManifest 分块清单，分块key由key、写入时间和分块序号生成，同一个key重新写入时不会读到旧的分块
+type chunkManifest struct {
+	timestamp int64 // 写入时间, 与头部一致
+	count     int   // 分块数
+	size      int   // 数据总长度
+}
+
+// chunkKey 第i个分块的key
+func chunkKey(key string, timestamp int64, i int) string {
+	return chunkKeyPrefix + key + ":" + strconv.FormatInt(timestamp, 36) + ":" + strconv.Itoa(i)
+}
+
+// isChunkKey 是否为分块key，遍历时跳过
+func isChunkKey(key string) bool {
+	return strings.HasPrefix(key, chunkKeyPrefix)
+}
+
+// encode 序列化分块清单
+func (m chunkManifest) encode() []byte {
+	b := make([]byte, chunkManifestSize)
+	binary.LittleEndian.PutUint32(b[:4], uint32(m.count))
+	binary.LittleEndian.PutUint64(b[4:], uint64(m.size))
+	return b
+}
+
+// decodeChunkManifest 解析分块清单
+func decodeChunkManifest(h entryHeader, data []byte) (chunkManifest, error) {
+	if len(data) != chunkManifestSize {
+		return chunkManifest{}, ErrInvalidEntry
+	}
+	return chunkManifest{
+		timestamp: h.timestamp,
+		count:     int(binary.LittleEndian.Uint32(data[:4])),
+		size:      int(binary.LittleEndian.Uint64(data[4:])),
+	}, nil
+}
+
+// writeChunks 按chunkSize分块保存data，先写分块再写清单，保证读到清单时分块已写入
+func (c *Cache) writeChunks(key string, h entryHeader, data []byte) error {
+	m := chunkManifest{timestamp: h.timestamp, size: len(data)}
+	buf := getEntryBuf()
+	defer putEntryBuf(buf)
+	for off := 0; off < len(data); off += c.chunkSize {
+		end := off + c.chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		*buf = append(append((*buf)[:0], emptyHeader[:]...), data[off:end]...)
+		putHeader(*buf, h)
+		if err := c.st.Set(chunkKey(key, h.timestamp, m.count), *buf); err != nil {
+			c.deleteChunks(key, m)
+			return err
+		}
+		m.count++
+	}
+	h.flags |= flagChunked
+	if err := c.st.Set(key, wrapEntry(h, m.encode())); err != nil {
+		c.deleteChunks(key, m)
+		return err
+	}
+	return nil
+}
+
+// readChunks 使用get根据清单读取并拼接分块，任一分块不存在(被淘汰或过期清除)时返回bigcache.ErrEntryNotFound
+func (c *Cache) readChunks(key string, h entryHeader, data []byte, get func(string) ([]byte, error)) ([]byte, error) {
+	m, err := decodeChunkManifest(h, data)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 0, m.size)
+	for i := 0; i < m.count; i++ {
+		entry, err := get(chunkKey(key, m.timestamp, i))
+		if err != nil {
+			return nil, bigcache.ErrEntryNotFound
+		}
+		_, part, err := unwrapEntry(entry)
+		if err != nil {
+			return nil, bigcache.ErrEntryNotFound
+		}
+		buf = append(buf, part...)
+	}
+	if len(buf) != m.size {
+		return nil, bigcache.ErrEntryNotFound
+	}
+	return buf, nil
+}
+
+// getChunk 读取数据时读取分块，分块是内部数据，不计入命中统计；lru/tinylfu存储同时更新分块的淘汰顺序，
+// 避免热点数据的分块先于清单被淘汰
+func (c *Cache) getChunk(key string) ([]byte, error) {
+	if ms, ok := c.st.(*memStore); ok {
+		return ms.access(key)
+	}
+	return c.st.Peek(key)
+}
+
+// chunkManifestOf 获取key当前的分块清单，未开启分块或数据未分块返回nil
+func (c *Cache) chunkManifestOf(key string) *chunkManifest {
+	if c.chunkSize <= 0 {
+		return nil
+	}
+	// 写入和删除时查询旧清单，不能计入命中统计或影响淘汰顺序
+	entry, err := c.st.Peek(key)
+	if err != nil {
+		return nil
+	}
+	h, data, err := unwrapEntry(entry)
+	if err != nil || h.flags&flagChunked == 0 {
+		return nil
+	}
+	m, err := decodeChunkManifest(h, data)
+	if err != nil {
+		return nil
+	}
+	return &m
+}
+
+// deleteChunks 删除清单对应的分块
+func (c *Cache) deleteChunks(key string, m chunkManifest) {
+	for i := 0; i < m.count; i++ {
+		_ = c.st.Delete(chunkKey(key, m.timestamp, i))
+	}
+}
@@ -0,0 +1,85 @@
+package lc
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestChunking 单测大数据分块保存
+func TestChunking(t *testing.T) {
+	convey.Convey("TestChunking", t, func() {
+		large := bytes.Repeat([]byte("0123456789"), 512*1024)
+		noChunk := createCache("test-no-chunk", WithShards(16), WithHardMaxCacheSize(64))
+		defer noChunk.Close()
+		convey.So(noChunk.Set("key", large), convey.ShouldNotBeNil)
+
+		cache := createCache("test-chunk", WithShards(16), WithHardMaxCacheSize(64), WithChunking(1024*1024))
+		defer cache.Close()
+		convey.So(cache.Set("key", large), convey.ShouldBeNil)
+		var v []byte
+		convey.So(cache.Get("key", &v), convey.ShouldBeNil)
+		convey.So(bytes.Equal(v, large), convey.ShouldBeTrue)
+		convey.So(cache.Len(), convey.ShouldEqual, 1+5)
+
+		// 遍历只返回拼接后的数据
+		n := 0
+		it := cache.Iterator()
+		for it.SetNext() {
+			info, err := it.Value()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(info.Key(), convey.ShouldEqual, "key")
+			convey.So(len(info.Value()), convey.ShouldEqual, len(large))
+			n++
+		}
+		convey.So(n, convey.ShouldEqual, 1)
+
+		// 重新写入删除旧的分块
+		convey.So(cache.Set("key", "small"), convey.ShouldBeNil)
+		convey.So(cache.Len(), convey.ShouldEqual, 1)
+		s, err := cache.GetString("key")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(s, convey.ShouldEqual, "small")
+		// 写入时查询旧的分块清单和读取分块都不计入命中统计
+		convey.So(cache.Stats().Hits, convey.ShouldEqual, 2)
+		convey.So(cache.Stats().Misses, convey.ShouldEqual, 0)
+
+		// 分块缺失视为不存在
+		convey.So(cache.Set("key", large), convey.ShouldBeNil)
+		m := cache.chunkManifestOf("key")
+		convey.So(m, convey.ShouldNotBeNil)
+		convey.So(cache.st.Delete(chunkKey("key", m.timestamp, 2)), convey.ShouldBeNil)
+		convey.So(cache.Get("key", &v), convey.ShouldEqual, ErrRecordNotFound)
+
+		// 删除时同时删除分块
+		convey.So(cache.Delete("key"), convey.ShouldBeNil)
+		convey.So(cache.Len(), convey.ShouldEqual, 0)
+	})
+}
+
+// TestChunkingLRU 单测lru存储读取时分块与清单一起更新淘汰顺序
+func TestChunkingLRU(t *testing.T) {
+	convey.Convey("TestChunkingLRU", t, func() {
+		cache := createCache("test-chunk-lru", WithShards(1), WithHardMaxCacheSize(1), WithEvictionPolicy(EvictionLRU),
+			WithChunking(300))
+		defer cache.Close()
+		hot := bytes.Repeat([]byte("h"), 900)
+		convey.So(cache.Set("hot", hot), convey.ShouldBeNil)
+		cold := bytes.Repeat([]byte("c"), 200)
+		misses := 0
+		for i := 0; i < 50; i++ {
+			var v []byte
+			if err := cache.Get("hot", &v); err != nil || !bytes.Equal(v, hot) {
+				misses++
+			}
+			for j := 0; j < 200; j++ {
+				convey.So(cache.Set("cold:"+strconv.Itoa(i*200+j), cold), convey.ShouldBeNil)
+			}
+		}
+		convey.So(misses, convey.ShouldEqual, 0)
+		// 分块读取不计入命中统计
+		convey.So(cache.Stats().Hits, convey.ShouldEqual, 50)
+	})
+}
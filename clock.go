@@ -0,0 +1,68 @@
+package lc
+
+import "time"
+
+// Clock 时钟，用于判断数据是否过期并驱动定期清理，单测中可使用lctest.FakeClock控制时间
+// 熔断窗口和写入准入窗口同样使用该时钟；穿透重试、排队等待和统计上报使用真实时间
+type Clock interface {
+	// Now 当前时间
+	Now() time.Time
+	// After 等待d后返回当前时间
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock 系统时钟
+type systemClock struct{}
+
+// Now 当前时间
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After 等待d后返回当前时间
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// isSystemClock 是否为系统时钟
+func isSystemClock(clock Clock) bool {
+	_, ok := clock.(systemClock)
+	return ok
+}
+
+// runEvery 每隔window按clock执行一次fn，done关闭后退出
+func runEvery(clock Clock, window time.Duration, done <-chan struct{}, fn func()) {
+	for {
+		select {
+		case <-clock.After(window):
+			fn()
+		case <-done:
+			return
+		}
+	}
+}
+
+// bigcacheNoExpiry 自定义时钟时bigcache的生命周期，避免bigcache按真实时间淘汰数据
+const bigcacheNoExpiry = 100 * 365 * 24 * time.Hour
+
+// removeExpired 删除bigcache中按时钟已过期的数据，用于自定义时钟
+func (c *Cache) removeExpired() {
+	it := c.st.Iterator()
+	for it.SetNext() {
+		key, _, entry, err := it.Value()
+		if err != nil || !c.isExpired(entry) {
+			continue
+		}
+		if isChunkKey(key) {
+			// 分块与清单的写入时间相同，分块过期时清单也已过期
+			_ = c.st.Delete(key)
+			continue
+		}
+		unlock := c.locks.lock(key)
+		// 加锁后重新读取，避免删除并发写入的新数据
+		if entry, err := c.st.Get(key); err == nil && c.isExpired(entry) {
+			_ = c.remove(key)
+		}
+		unlock()
+	}
+}
@@ -0,0 +1,75 @@
+package lc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/smartystreets/goconvey/convey"
+
+	"trpc.group/trpc-go/trpc-go/codec"
+
+	"github.com/trpc-extend/lc/lctest"
+)
+
+// TestClock 单测使用可控制的时钟验证过期、兜底、清理和熔断恢复
+func TestClock(t *testing.T) {
+	convey.Convey("TestClock", t, func() {
+		json := codec.SerializationTypeJSON
+		fail := func() (interface{}, error) { return nil, errors.New("load fail") }
+		for _, opt := range []Option{WithEvictionPolicy(EvictionFIFO), WithEvictionPolicy(EvictionLRU),
+			WithObjectMode(100)} {
+			clock := lctest.NewFakeClock(time.Time{})
+			cache := createCache("test-clock", WithShards(4), WithHardMaxCacheSize(16), WithLifeWindow(time.Minute),
+				WithCleanWindow(10*time.Minute), WithAllowUseExpiredEntry(true), WithClock(clock), opt)
+			v := "v1"
+			convey.So(cache.Set("key", &v, json), convey.ShouldBeNil)
+			ttl, err := cache.TTL("key")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(ttl, convey.ShouldEqual, time.Minute)
+
+			// 过期未清除，穿透失败使用过期数据兜底
+			clock.AdvanceAndWait(time.Minute, 1)
+			var got string
+			status, err := cache.GetWithEntryStatus("key", &got, json)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(status, convey.ShouldEqual, bigcache.Expired)
+			got = ""
+			convey.So(cache.GetWithLoad(context.Background(), "key", &got, fail, json), convey.ShouldBeNil)
+			convey.So(got, convey.ShouldEqual, "v1")
+
+			// 穿透成功后刷新
+			convey.So(cache.GetWithLoad(context.Background(), "key", &got, func() (interface{}, error) {
+				v := "v2"
+				return &v, nil
+			}, json), convey.ShouldBeNil)
+			convey.So(got, convey.ShouldEqual, "v2")
+			ttl, _ = cache.TTL("key")
+			convey.So(ttl, convey.ShouldEqual, time.Minute)
+
+			// 到达清理周期后删除过期数据
+			clock.AdvanceAndWait(10*time.Minute, 1)
+			convey.So(cache.Get("key", &got), convey.ShouldEqual, ErrRecordNotFound)
+			convey.So(cache.Len(), convey.ShouldEqual, 0)
+			cache.Close()
+		}
+
+		// 熔断打开后按时钟进入半开状态
+		clock := lctest.NewFakeClock(time.Time{})
+		cache := createCache("test-clock-breaker", WithClock(clock), WithCleanWindow(0),
+			WithCircuitBreaker(BreakerConfig{MinRequests: 1, OpenTimeout: time.Second}))
+		defer cache.Close()
+		var got string
+		convey.So(cache.GetWithLoad(context.Background(), "key", &got, fail), convey.ShouldNotBeNil)
+		convey.So(cache.GetWithLoad(context.Background(), "key", &got, fail), convey.ShouldEqual, ErrCircuitOpen)
+		clock.Advance(time.Second)
+		convey.So(cache.Stats().Breaker.State, convey.ShouldEqual, BreakerOpen)
+		convey.So(cache.GetWithLoad(context.Background(), "key", &got, func() (interface{}, error) {
+			ok := "ok"
+			return &ok, nil
+		}), convey.ShouldBeNil)
+		convey.So(cache.Stats().Breaker.State, convey.ShouldEqual, BreakerClosed)
+	})
+}
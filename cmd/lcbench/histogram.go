@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math/bits"
+	"time"
+)
+
+// histogramSubBuckets 每个2的幂区间再等分的桶数，误差约为1/histogramSubBuckets
+const histogramSubBuckets = 16
+
+// histogram 对数分桶的延迟直方图，内存固定，记录不分配内存
+type histogram struct {
+	counts [64 * histogramSubBuckets]int64
+	total  int64
+	max    time.Duration
+}
+
+// bucket 延迟所在的桶: 高位为最高有效位的位置，低位为其后4位
+func bucket(ns uint64) int {
+	if ns < histogramSubBuckets {
+		return int(ns)
+	}
+	n := bits.Len64(ns)
+	return n*histogramSubBuckets + int(ns>>(n-5)&(histogramSubBuckets-1))
+}
+
+// bucketValue 桶的下界
+func bucketValue(i int) time.Duration {
+	if i < 2*histogramSubBuckets {
+		return time.Duration(i)
+	}
+	n := i / histogramSubBuckets
+	return time.Duration(uint64(histogramSubBuckets+i%histogramSubBuckets) << (n - 5))
+}
+
+// record 记录一次延迟
+func (h *histogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.counts[bucket(uint64(d))]++
+	h.total++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// merge 合并其他直方图
+func (h *histogram) merge(o *histogram) {
+	for i, n := range o.counts {
+		h.counts[i] += n
+	}
+	h.total += o.total
+	if o.max > h.max {
+		h.max = o.max
+	}
+}
+
+// quantile 返回q分位的延迟
+func (h *histogram) quantile(q float64) time.Duration {
+	target := int64(q * float64(h.total))
+	var seen int64
+	for i, n := range h.counts {
+		if seen += n; n > 0 && seen > target {
+			return bucketValue(i)
+		}
+	}
+	return h.max
+}
@@ -0,0 +1,267 @@
+// Command lcbench 按配置的负载压测lc.Cache，输出吞吐、延迟分位数、命中率、GC停顿和内存，用于调整Shards、MaxEntrySize、LifeWindow等参数
+//
+// 用法:
+//
+//	lcbench -policy lru -keys 1000000 -dist zipf -reads 0.9 -value-size 512 -duration 30s
+//	lcbench -policy fifo -shards 256 -load-latency 2ms -life 10s
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"trpc.group/trpc-go/trpc-go/log"
+
+	"github.com/trpc-extend/lc"
+)
+
+// options 压测配置
+type options struct {
+	policy       string
+	shards       int
+	maxEntrySize int
+	life         time.Duration
+	clean        time.Duration
+	size         int
+	keys         int
+	dist         string
+	zipfS        float64
+	valueSize    int
+	valueSizeMax int
+	reads        float64
+	loadLatency  time.Duration
+	duration     time.Duration
+	concurrency  int
+	warmup       bool
+}
+
+func main() {
+	var o options
+	flag.StringVar(&o.policy, "policy", "fifo", "淘汰策略: fifo/lru/tinylfu/object(对象模式)")
+	flag.IntVar(&o.shards, "shards", 128, "分片数，必须是2的幂")
+	flag.IntVar(&o.maxEntrySize, "max-entry-size", 1024, "预估的单条数据大小，字节")
+	flag.DurationVar(&o.life, "life", time.Minute, "数据生命周期")
+	flag.DurationVar(&o.clean, "clean", 30*time.Second, "清理过期数据的周期")
+	flag.IntVar(&o.size, "size", 1024, "cache内存上限，MB")
+	flag.IntVar(&o.keys, "keys", 100000, "key的个数")
+	flag.StringVar(&o.dist, "dist", "zipf", "key的分布: zipf/uniform")
+	flag.Float64Var(&o.zipfS, "zipf-s", 1.1, "zipf分布参数s，必须大于1，越大越集中")
+	flag.IntVar(&o.valueSize, "value-size", 256, "value字节数")
+	flag.IntVar(&o.valueSizeMax, "value-size-max", 0, "大于value-size时value字节数在[value-size, value-size-max]内随机")
+	flag.Float64Var(&o.reads, "reads", 0.9, "读请求比例，其余为写请求")
+	flag.DurationVar(&o.loadLatency, "load-latency", 0, "大于0时读请求使用GetWithLoad，穿透函数耗时为该值")
+	flag.DurationVar(&o.duration, "duration", 10*time.Second, "压测时长")
+	flag.IntVar(&o.concurrency, "concurrency", runtime.GOMAXPROCS(0), "并发数")
+	flag.BoolVar(&o.warmup, "warmup", true, "压测前写入所有key")
+	flag.Parse()
+
+	// 穿透成功会输出debug日志，压测时只输出错误
+	log.SetLevel("0", log.LevelError)
+	if err := run(o); err != nil {
+		fmt.Fprintln(os.Stderr, "lcbench:", err)
+		os.Exit(1)
+	}
+}
+
+// run 执行压测并输出报告
+func run(o options) error {
+	if o.dist == "zipf" && o.zipfS <= 1 {
+		return fmt.Errorf("zipf-s must be greater than 1")
+	}
+	if o.dist != "zipf" && o.dist != "uniform" {
+		return fmt.Errorf("unknown key distribution %q", o.dist)
+	}
+	opts := []lc.Option{
+		lc.WithShards(o.shards),
+		lc.WithMaxEntrySize(o.maxEntrySize),
+		lc.WithMaxEntriesInWindow(o.keys),
+		lc.WithLifeWindow(o.life),
+		lc.WithCleanWindow(o.clean),
+		lc.WithHardMaxCacheSize(o.size),
+		lc.WithAllowUseExpiredEntry(true),
+	}
+	if o.policy == "object" {
+		opts = append(opts, lc.WithObjectMode(o.keys))
+	} else {
+		opts = append(opts, lc.WithEvictionPolicy(lc.EvictionPolicy(o.policy)))
+	}
+	lc.RegisterCache("lcbench", opts...)
+	cache := lc.GetCache("lcbench")
+	defer cache.Close()
+
+	keys := make([]string, o.keys)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+	}
+	maxSize := o.valueSize
+	if o.valueSizeMax > maxSize {
+		maxSize = o.valueSizeMax
+	}
+	values := make([]byte, maxSize)
+	rand.New(rand.NewSource(1)).Read(values)
+	if o.warmup {
+		for _, key := range keys {
+			_ = cache.Set(key, values[:o.valueSize])
+		}
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	statsBefore := cache.Stats()
+	ctx, cancel := context.WithTimeout(context.Background(), o.duration)
+	defer cancel()
+	results := make([]*workerResult, o.concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range results {
+		results[i] = &workerResult{}
+		wg.Add(1)
+		go func(w *workerResult, seed int64) {
+			defer wg.Done()
+			w.run(ctx, cache, o, keys, values, seed)
+		}(results[i], int64(i)+1)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	total := &workerResult{}
+	for _, w := range results {
+		total.merge(w)
+	}
+	stats := cache.Stats()
+	total.hits = stats.Hits - statsBefore.Hits
+	total.misses = stats.Misses - statsBefore.Misses
+	report(o, cache, total, elapsed, &before, &after)
+	return nil
+}
+
+// workerResult 单个压测协程的统计
+type workerResult struct {
+	reads, writes, loads, errors int64
+	readLatency, writeLatency    histogram
+	hits, misses                 int64 // cache命中统计, 汇总时从Stats获取
+}
+
+// run 按配置执行读写直到ctx结束
+func (w *workerResult) run(ctx context.Context, cache *lc.Cache, o options, keys []string, values []byte,
+	seed int64) {
+	r := rand.New(rand.NewSource(seed))
+	var zipf *rand.Zipf
+	if o.dist == "zipf" {
+		zipf = rand.NewZipf(r, o.zipfS, 1, uint64(len(keys)-1))
+	}
+	var dst []byte
+	for i := 0; ; i++ {
+		// 每1024次检查一次是否结束，避免select影响测量
+		if i&1023 == 0 && ctx.Err() != nil {
+			return
+		}
+		var key string
+		if zipf != nil {
+			key = keys[zipf.Uint64()]
+		} else {
+			key = keys[r.Intn(len(keys))]
+		}
+		size := o.valueSize
+		if o.valueSizeMax > o.valueSize {
+			size += r.Intn(o.valueSizeMax - o.valueSize + 1)
+		}
+		if r.Float64() >= o.reads {
+			begin := time.Now()
+			err := cache.Set(key, values[:size])
+			w.writeLatency.record(time.Since(begin))
+			w.writes++
+			if err != nil {
+				w.errors++
+			}
+			continue
+		}
+		begin := time.Now()
+		var err error
+		if o.loadLatency > 0 {
+			loaded := false
+			err = cache.GetWithLoad(ctx, key, &dst, func() (interface{}, error) {
+				loaded = true
+				time.Sleep(o.loadLatency)
+				v := values[:size]
+				return &v, nil
+			})
+			if loaded {
+				w.loads++
+			}
+		} else {
+			if err = cache.Get(key, &dst); err == lc.ErrRecordNotFound {
+				err = nil
+			}
+		}
+		w.readLatency.record(time.Since(begin))
+		w.reads++
+		if err != nil && ctx.Err() == nil {
+			w.errors++
+		}
+	}
+}
+
+// merge 合并其他协程的统计
+func (w *workerResult) merge(o *workerResult) {
+	w.reads += o.reads
+	w.writes += o.writes
+	w.loads += o.loads
+	w.errors += o.errors
+	w.readLatency.merge(&o.readLatency)
+	w.writeLatency.merge(&o.writeLatency)
+}
+
+// report 输出压测报告
+func report(o options, cache *lc.Cache, r *workerResult, elapsed time.Duration, before, after *runtime.MemStats) {
+	ops := r.reads + r.writes
+	fmt.Printf("config: policy=%s shards=%d max_entry_size=%d life=%v size=%dMB keys=%d dist=%s value_size=%d",
+		o.policy, o.shards, o.maxEntrySize, o.life, o.size, o.keys, o.dist, o.valueSize)
+	if o.valueSizeMax > o.valueSize {
+		fmt.Printf("-%d", o.valueSizeMax)
+	}
+	fmt.Printf(" reads=%.2f load_latency=%v concurrency=%d\n", o.reads, o.loadLatency, o.concurrency)
+	fmt.Printf("throughput: %.0f ops/s (%d ops in %v), errors: %d\n", float64(ops)/elapsed.Seconds(), ops,
+		elapsed.Round(time.Millisecond), r.errors)
+	if r.reads > 0 {
+		hitRatio := 0.0
+		if r.hits+r.misses > 0 {
+			hitRatio = float64(r.hits) * 100 / float64(r.hits+r.misses)
+		}
+		fmt.Printf("read:  %d ops, hit ratio %.2f%%, loads %d, p50 %v, p99 %v, p999 %v, max %v\n", r.reads,
+			hitRatio, r.loads, r.readLatency.quantile(0.5), r.readLatency.quantile(0.99),
+			r.readLatency.quantile(0.999), r.readLatency.max)
+	}
+	if r.writes > 0 {
+		fmt.Printf("write: %d ops, p50 %v, p99 %v, p999 %v, max %v\n", r.writes, r.writeLatency.quantile(0.5),
+			r.writeLatency.quantile(0.99), r.writeLatency.quantile(0.999), r.writeLatency.max)
+	}
+	gcs := after.NumGC - before.NumGC
+	fmt.Printf("gc: %d cycles, total pause %v, max pause %v\n", gcs,
+		time.Duration(after.PauseTotalNs-before.PauseTotalNs), maxPause(after, gcs))
+	fmt.Printf("memory: heap %dMB, sys %dMB, cache len %d, cache capacity %dMB\n", after.HeapAlloc>>20,
+		after.Sys>>20, cache.Len(), cache.Capacity()>>20)
+}
+
+// maxPause 最近gcs次GC的最大停顿，runtime只保留最近256次
+func maxPause(ms *runtime.MemStats, gcs uint32) time.Duration {
+	if gcs > uint32(len(ms.PauseNs)) {
+		gcs = uint32(len(ms.PauseNs))
+	}
+	var max uint64
+	for i := uint32(0); i < gcs; i++ {
+		if p := ms.PauseNs[(ms.NumGC-1-i)%uint32(len(ms.PauseNs))]; p > max {
+			max = p
+		}
+	}
+	return time.Duration(max)
+}
@@ -0,0 +1,158 @@
+// Command lcdump 在lc二进制快照和JSON Lines之间转换，用于查看线上导出的cache数据或在本地重放
+// 逐条读取并写出，不经过cache，不受内存限制，不会丢失数据
+//
+// 用法:
+//
+//	lcdump -from snapshot -in cache.snap -out cache.jsonl -life 60s -decode
+//	lcdump -from jsonl -in cache.jsonl -out cache.snap -life 60s
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/trpc-extend/lc"
+)
+
+func main() {
+	from := flag.String("from", "snapshot", "输入格式: snapshot/jsonl，输出为另一种格式")
+	in := flag.String("in", "-", "输入文件，-表示标准输入")
+	out := flag.String("out", "-", "输出文件，-表示标准输出")
+	life := flag.Duration("life", 60*time.Second, "cache的生命周期，用于计算剩余生命周期，需与导出的cache一致")
+	decode := flag.Bool("decode", false, "输出jsonl时将UTF-8文本数据同时以字符串输出到decoded")
+	flag.Parse()
+
+	if err := run(*from, *in, *out, *life, *decode); err != nil {
+		fmt.Fprintln(os.Stderr, "lcdump:", err)
+		os.Exit(1)
+	}
+}
+
+// run 逐条读取输入并按另一种格式写出
+func run(from, in, out string, life time.Duration, decode bool) error {
+	r, err := openInput(in)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := openOutput(out)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	var n int
+	switch from {
+	case "snapshot":
+		n, err = snapshotToJSONL(r, w, life, decode)
+	case "jsonl":
+		n, err = jsonlToSnapshot(r, w, life)
+	default:
+		return fmt.Errorf("unknown input format %q", from)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "lcdump: converted %d entries\n", n)
+	return nil
+}
+
+// snapshotToJSONL 将二进制快照转换为JSONL，按life计算剩余生命周期
+func snapshotToJSONL(r io.Reader, w io.Writer, life time.Duration, decode bool) (int, error) {
+	sr := lc.NewSnapshotReader(r, 0)
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	now := time.Now()
+	n := 0
+	for {
+		e, err := sr.Next()
+		if err == io.EOF {
+			return n, bw.Flush()
+		}
+		if err != nil {
+			return n, err
+		}
+		t := time.Unix(0, e.Timestamp)
+		ttl := life - now.Sub(t)
+		if ttl < 0 {
+			ttl = 0
+		}
+		entry := lc.JSONLEntry{
+			Key:   e.Key,
+			Value: e.Value,
+			TTL:   ttl.Milliseconds(),
+			Time:  t,
+			Size:  len(e.Value),
+		}
+		if decode {
+			entry.Decoded = decodeText(e.Value)
+		}
+		if err := enc.Encode(&entry); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// jsonlToSnapshot 将JSONL转换为二进制快照，有time时保留原写入时间，否则按ttl_ms和life计算
+func jsonlToSnapshot(r io.Reader, w io.Writer, life time.Duration) (int, error) {
+	dec := json.NewDecoder(r)
+	sw := lc.NewSnapshotWriter(w)
+	now := time.Now()
+	n := 0
+	for {
+		var e lc.JSONLEntry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return n, sw.Flush()
+		}
+		if err != nil {
+			return n, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		err = sw.Write(lc.SnapshotEntry{Key: e.Key, Timestamp: e.WriteTime(now, life).UnixNano(), Value: e.Value})
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// decodeText UTF-8文本以字符串输出，其他数据不解析
+func decodeText(data []byte) interface{} {
+	if !utf8.Valid(data) {
+		return nil
+	}
+	return string(data)
+}
+
+// openInput 打开输入文件
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// openOutput 创建输出文件
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+// nopWriteCloser 标准输出不关闭
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close 不关闭
+func (nopWriteCloser) Close() error {
+	return nil
+}
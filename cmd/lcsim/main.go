@@ -0,0 +1,359 @@
+// Command lcsim 用WithAccessTrace或rpc_cache access_trace记录的访问重放到不同生命周期、内存上限和淘汰策略的cache，
+// 输出命中率和内存随时间的变化，用于上线或调整cache前离线评估配置
+//
+// 用法:
+//
+//	lcsim -trace access.trace -policies fifo,lru,tinylfu -sizes 64,256,1024 -ttls 30s,1m,10m
+//	lcsim -trace access.trace -policies lru -sizes 512 -ttls 5m -interval 10m -curve
+//
+// 重放使用lctest.FakeClock按记录的时间推进，过期和清理与线上一致；按key采样的记录中数据大小按采样比例放大，
+// 内存上限即为全量访问下的内存上限。读取未命中时按穿透模型写入，数据大小取该key最近一次记录的大小。
+//
+// peak_mb/final_mb和曲线中的live_mb为cache中存活数据(key+数据，含已过期未清除的数据)的大小，
+// alloc_mb为cache.Capacity()，fifo(bigcache)为预分配的容量，不随数据淘汰减少。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"trpc.group/trpc-go/trpc-go/log"
+
+	"github.com/trpc-extend/lc"
+	"github.com/trpc-extend/lc/lctest"
+)
+
+// options 重放配置
+type options struct {
+	trace       string
+	policies    []string
+	sizes       []int
+	ttls        []time.Duration
+	shards      int
+	clean       time.Duration
+	defaultSize int
+	interval    time.Duration
+	curve       bool
+}
+
+func main() {
+	var (
+		o                     options
+		policies, sizes, ttls string
+	)
+	flag.StringVar(&o.trace, "trace", "", "访问记录文件")
+	flag.StringVar(&policies, "policies", "fifo,lru,tinylfu", "淘汰策略，逗号分隔: fifo/lru/tinylfu")
+	flag.StringVar(&sizes, "sizes", "256", "cache内存上限，MB，逗号分隔")
+	flag.StringVar(&ttls, "ttls", "1m", "数据生命周期，逗号分隔")
+	flag.IntVar(&o.shards, "shards", 16, "分片数，必须是2的幂")
+	flag.DurationVar(&o.clean, "clean", 0, "清理过期数据的周期，0表示使用生命周期的一半")
+	flag.IntVar(&o.defaultSize, "default-size", 1024, "未记录过大小的key的数据字节数")
+	flag.DurationVar(&o.interval, "interval", time.Minute, "命中率和内存曲线的采样周期(按记录的时间)")
+	flag.BoolVar(&o.curve, "curve", false, "输出每个配置的命中率和内存曲线")
+	flag.Parse()
+
+	// 重放过程中穿透、淘汰等日志没有意义，只输出错误
+	log.SetLevel("0", log.LevelError)
+	var err error
+	if o.policies, err = parseList(policies, func(s string) (string, error) { return s, nil }); err == nil {
+		if o.sizes, err = parseList(sizes, strconv.Atoi); err == nil {
+			o.ttls, err = parseList(ttls, time.ParseDuration)
+		}
+	}
+	if err == nil && o.trace == "" {
+		err = fmt.Errorf("-trace is required")
+	}
+	for _, ttl := range o.ttls {
+		if err == nil && ttl <= 0 {
+			err = fmt.Errorf("ttl must be positive")
+		}
+	}
+	if err == nil {
+		err = run(o)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "lcsim:", err)
+		os.Exit(1)
+	}
+}
+
+// parseList 解析逗号分隔的参数
+func parseList[T any](s string, parse func(string) (T, error)) ([]T, error) {
+	var list []T
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		v, err := parse(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q: %w", field, err)
+		}
+		list = append(list, v)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("empty list %q", s)
+	}
+	return list, nil
+}
+
+// run 按所有配置组合重放并输出结果
+func run(o options) error {
+	var results []*result
+	for _, policy := range o.policies {
+		for _, ttl := range o.ttls {
+			for _, size := range o.sizes {
+				r, err := replay(o, simConfig{policy: policy, ttl: ttl, size: size}, len(results))
+				if err != nil {
+					return err
+				}
+				results = append(results, r)
+			}
+		}
+	}
+	if len(results) > 0 {
+		r := results[0]
+		fmt.Printf("trace: %s, %d records, sample rate %g, duration %v\n", o.trace, r.records, r.sampleRate,
+			r.duration.Round(time.Second))
+	}
+	fmt.Printf("%-8s %10s %8s %10s %10s %10s %12s %12s %12s\n", "policy", "ttl", "size_mb", "reads", "hit_ratio",
+		"rejected", "peak_mb", "final_mb", "alloc_mb")
+	for _, r := range results {
+		fmt.Printf("%-8s %10v %8d %10d %9.2f%% %10d %12.1f %12.1f %12.1f\n", r.cfg.policy, r.cfg.ttl, r.cfg.size,
+			r.reads, ratio(r.hits, r.reads), r.rejected, r.peakMB, r.finalMB, r.allocMB)
+	}
+	if !o.curve {
+		return nil
+	}
+	for _, r := range results {
+		fmt.Printf("\n# policy=%s ttl=%v size=%dMB\n", r.cfg.policy, r.cfg.ttl, r.cfg.size)
+		fmt.Printf("%10s %10s %14s %10s %10s %10s\n", "elapsed", "hit_ratio", "interval_ratio", "len", "live_mb",
+			"alloc_mb")
+		for _, p := range r.curve {
+			fmt.Printf("%10v %9.2f%% %13.2f%% %10d %10.1f %10.1f\n", p.elapsed, ratio(p.hits, p.reads),
+				ratio(p.intervalHits, p.intervalReads), p.len, p.liveMB, p.allocMB)
+		}
+	}
+	return nil
+}
+
+// simConfig 一组重放配置
+type simConfig struct {
+	policy string
+	ttl    time.Duration
+	size   int
+}
+
+// result 一组配置的重放结果
+type result struct {
+	cfg                         simConfig
+	sampleRate                  float64
+	records                     int
+	duration                    time.Duration
+	reads, hits, rejected       int64
+	peakMB, finalMB             float64 // 存活数据大小的峰值和结束时的值
+	allocMB                     float64 // 结束时cache占用的内存
+	curve                       []point
+	intervalReads, intervalHits int64 // 当前采样周期的读取和命中次数
+}
+
+// point 曲线上的一个采样点
+type point struct {
+	elapsed                     time.Duration
+	reads, hits                 int64
+	intervalReads, intervalHits int64
+	len                         int
+	liveMB, allocMB             float64
+}
+
+// replay 按cfg创建cache并重放访问记录
+func replay(o options, cfg simConfig, id int) (*result, error) {
+	f, err := os.Open(o.trace)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tr, err := lc.NewAccessTraceReader(f)
+	if err != nil {
+		return nil, err
+	}
+	first, err := tr.Next()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty trace %s", o.trace)
+	}
+	if err != nil {
+		return nil, err
+	}
+	clean := o.clean
+	if clean <= 0 {
+		clean = cfg.ttl / 2
+	}
+	start := time.Unix(0, first.Time)
+	clock := lctest.NewFakeClock(start)
+	name := fmt.Sprintf("lcsim-%d", id)
+	lc.RegisterCache(name, lc.WithClock(clock), lc.WithEvictionPolicy(lc.EvictionPolicy(cfg.policy)),
+		lc.WithShards(o.shards), lc.WithLifeWindow(cfg.ttl), lc.WithCleanWindow(clean),
+		lc.WithHardMaxCacheSize(cfg.size), lc.WithMaxEntrySize(o.defaultSize))
+	cache := lc.GetCache(name)
+	defer cache.Close()
+	// 等待清理协程开始等待时钟，之后每次推进时钟都等待清理完成，保证重放结果确定
+	clock.BlockUntil(1)
+
+	s := &simulator{
+		o:      o,
+		cache:  cache,
+		clock:  clock,
+		scale:  1 / tr.SampleRate(),
+		sizes:  make(map[uint64]int),
+		live:   make(map[uint64]struct{}),
+		result: &result{cfg: cfg, sampleRate: tr.SampleRate()},
+		next:   start.Add(o.interval),
+		start:  start,
+	}
+	rec := first
+	for {
+		if err := s.apply(rec); err != nil {
+			return nil, err
+		}
+		if rec, err = tr.Next(); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.sample(clock.Now())
+	s.result.duration = clock.Now().Sub(start)
+	last := s.curve[len(s.curve)-1]
+	s.result.finalMB, s.result.allocMB = last.liveMB, last.allocMB
+	return s.result, nil
+}
+
+// simulator 重放状态
+type simulator struct {
+	o     options
+	cache *lc.Cache
+	clock *lctest.FakeClock
+	scale float64
+	sizes map[uint64]int      // key最近一次记录的数据大小(已放大)
+	live  map[uint64]struct{} // 写入成功且未删除的key，采样时去掉已被淘汰或清除的key
+	buf   []byte
+	*result
+	next, start time.Time // 下一个曲线采样点的时间和重放开始时间
+}
+
+// apply 重放一条记录
+func (s *simulator) apply(rec lc.AccessRecord) error {
+	now := time.Unix(0, rec.Time)
+	for !s.next.After(now) {
+		s.advance(s.next)
+		s.sample(s.next)
+		s.next = s.next.Add(s.o.interval)
+	}
+	s.advance(now)
+	s.records++
+	key := strconv.FormatUint(rec.KeyHash, 36)
+	if rec.Size > 0 {
+		s.sizes[rec.KeyHash] = int(float64(rec.Size) * s.scale)
+	}
+	switch rec.Op {
+	case lc.AccessGetHit, lc.AccessGetMiss, lc.AccessRequest:
+		s.reads++
+		s.intervalReads++
+		status, err := s.cache.GetWithEntryStatus(key, nil)
+		if err == nil && status != bigcache.Expired {
+			s.hits++
+			s.intervalHits++
+			return nil
+		}
+		// 未命中时穿透写入
+		s.set(key, rec.KeyHash)
+	case lc.AccessSet:
+		s.set(key, rec.KeyHash)
+	case lc.AccessDelete:
+		_ = s.cache.Delete(key)
+		delete(s.live, rec.KeyHash)
+	default:
+		return fmt.Errorf("unknown op %d at record %d", rec.Op, s.records)
+	}
+	return nil
+}
+
+// advance 推进时钟，触发清理时等待清理完成
+func (s *simulator) advance(t time.Time) {
+	if !t.After(s.clock.Now()) {
+		return
+	}
+	s.clock.Set(t)
+	s.clock.BlockUntil(1)
+}
+
+// set 按key最近一次记录的大小写入数据
+func (s *simulator) set(key string, hash uint64) {
+	size, ok := s.sizes[hash]
+	if !ok {
+		size = s.o.defaultSize
+	}
+	if cap(s.buf) < size {
+		s.buf = make([]byte, size)
+	}
+	if err := s.cache.Set(key, s.buf[:size]); err != nil {
+		// 超过分片大小等原因写入失败
+		s.rejected++
+		return
+	}
+	s.live[hash] = struct{}{}
+}
+
+// liveBytes 统计cache中存活数据的字节数，KeyStats不计入命中统计，不影响淘汰顺序
+func (s *simulator) liveBytes() int {
+	n := 0
+	for hash := range s.live {
+		key := strconv.FormatUint(hash, 36)
+		stats, err := s.cache.KeyStats(key)
+		if err != nil {
+			// 已被淘汰或清除
+			delete(s.live, hash)
+			continue
+		}
+		n += len(key) + stats.Size
+	}
+	return n
+}
+
+// sample 记录曲线采样点
+func (s *simulator) sample(t time.Time) {
+	live := mb(s.liveBytes())
+	if live > s.peakMB {
+		s.peakMB = live
+	}
+	s.curve = append(s.curve, point{
+		elapsed:       t.Sub(s.start),
+		reads:         s.reads,
+		hits:          s.hits,
+		intervalReads: s.intervalReads,
+		intervalHits:  s.intervalHits,
+		len:           s.cache.Len(),
+		liveMB:        live,
+		allocMB:       mb(s.cache.Capacity()),
+	})
+	s.intervalReads, s.intervalHits = 0, 0
+}
+
+// ratio 命中率百分比
+func ratio(hits, reads int64) float64 {
+	if reads == 0 {
+		return 0
+	}
+	return float64(hits) * 100 / float64(reads)
+}
+
+// mb 字节数转为MB
+func mb(n int) float64 {
+	return float64(n) / (1 << 20)
+}
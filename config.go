@@ -52,6 +52,46 @@ type Config struct {
 
 	// AllowUseExpiredEntry 允许使用未清除数据
 	AllowUseExpiredEntry bool `yaml:"allow_use_expired_entry"`
+	// Tracer 链路追踪，默认不追踪
+	Tracer Tracer `yaml:"-"`
+	// Breaker 穿透函数熔断配置，nil表示不熔断
+	Breaker *BreakerConfig `yaml:"breaker"`
+	// LoadTimeout 穿透函数(包含重试)的超时时间，<=0表示不超时
+	LoadTimeout time.Duration `yaml:"load_timeout"`
+	// Retry 穿透函数重试策略，nil表示不重试
+	Retry *RetryPolicy `yaml:"retry"`
+	// MaxConcurrentLoads 不同key的最大并发穿透数，<=0表示不限制
+	MaxConcurrentLoads int `yaml:"max_concurrent_loads"`
+	// LoadQueueTimeout 并发穿透数超限时的排队等待时间，<=0表示不等待
+	LoadQueueTimeout time.Duration `yaml:"load_queue_timeout"`
+	// SharedLoadResult GetWithLoad合并的请求共享同一个穿透结果对象，不拷贝，调用方必须只读
+	SharedLoadResult bool `yaml:"shared_load_result"`
+	// Serializer 序列化方式，调用时未指定序列化类型时使用
+	Serializer Serializer `yaml:"-"`
+	// SlidingExpiration 滑动过期，读取未过期的数据后重新计算生命周期
+	SlidingExpiration bool `yaml:"sliding_expiration"`
+	// EvictionPolicy 淘汰策略，默认fifo(bigcache)，lru/tinylfu按HardMaxCacheSize限制内存
+	EvictionPolicy EvictionPolicy `yaml:"eviction_policy"`
+	// Admission 写入准入配置，nil表示所有写入都保存
+	Admission *AdmissionConfig `yaml:"admission"`
+	// HotKeys 热点key统计配置，nil表示不统计
+	HotKeys *HotKeysConfig `yaml:"hot_keys"`
+	// MemoryWeight 设置内存预算后分配内存的权重，默认1
+	MemoryWeight float64 `yaml:"memory_weight"`
+	// MinMemory 设置内存预算后lru/tinylfu存储保证分配的内存，单位MB
+	MinMemory int `yaml:"min_memory"`
+	// AutoSize 根据容器内存限制自动设置HardMaxCacheSize，nil表示不自动设置
+	AutoSize *AutoSizeConfig `yaml:"auto_size"`
+	// ChunkSize 超过该字节数的数据分块保存，<=0表示不分块
+	ChunkSize int `yaml:"chunk_size"`
+	// ObjectMode 对象模式，直接保存Go对象不序列化，对象必须只读
+	ObjectMode bool `yaml:"object_mode"`
+	// MaxObjects 对象模式的最大数据条数，<=0时使用MaxEntriesInWindow
+	MaxObjects int `yaml:"max_objects"`
+	// Clock 判断过期和驱动定期清理的时钟，默认使用系统时钟
+	Clock Clock `yaml:"-"`
+	// AccessTrace 采样记录访问，用于lcsim离线评估不同配置的命中率，nil表示不记录
+	AccessTrace *AccessTraceConfig `yaml:"access_trace"`
 }
 
 // Option 声明cache的option
@@ -126,3 +166,151 @@ func WithAllowUseExpiredEntry(allowed bool) Option {
 		c.AllowUseExpiredEntry = allowed
 	}
 }
+
+// WithTracer 设置链路追踪，nil表示不追踪
+func WithTracer(t Tracer) Option {
+	return func(c *Config) {
+		if t == nil {
+			t = noopTracer{}
+		}
+		c.Tracer = t
+	}
+}
+
+// WithCircuitBreaker 开启穿透函数熔断，熔断期间GetWithLoad使用过期数据兜底或直接返回ErrCircuitOpen
+func WithCircuitBreaker(cfg BreakerConfig) Option {
+	return func(c *Config) {
+		c.Breaker = &cfg
+	}
+}
+
+// WithLoadTimeout 设置穿透函数(包含重试)的超时时间，超时后不再等待穿透函数，计为熔断失败，
+// GetWithLoad使用过期数据兜底或返回ErrLoadTimeout；GetWithLoadContext的穿透函数ctx在超时后取消，
+// 穿透函数在后台执行完成后丢弃结果
+func WithLoadTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.LoadTimeout = timeout
+	}
+}
+
+// WithRetryPolicy 设置穿透函数重试策略
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Config) {
+		c.Retry = &p
+	}
+}
+
+// WithLoadConcurrency 限制不同key的并发穿透数，超限时最多排队queueTimeout，
+// 超时后GetWithLoad使用过期数据兜底或返回ErrLoadOverloaded；熔断打开时不排队，
+// 许可在穿透函数返回时释放，穿透超时后在后台执行的穿透函数仍占用许可
+func WithLoadConcurrency(limit int, queueTimeout time.Duration) Option {
+	return func(c *Config) {
+		c.MaxConcurrentLoads = limit
+		c.LoadQueueTimeout = queueTimeout
+	}
+}
+
+// WithSharedLoadResult GetWithLoad合并的请求共享同一个穿透结果对象(浅拷贝)，
+// 省去拷贝开销，仅适用于只读的调用方
+func WithSharedLoadResult(shared bool) Option {
+	return func(c *Config) {
+		c.SharedLoadResult = shared
+	}
+}
+
+// WithSerializer 设置序列化方式，Get/Set/GetWithLoad未指定序列化类型时使用
+func WithSerializer(s Serializer) Option {
+	return func(c *Config) {
+		c.Serializer = s
+	}
+}
+
+// WithAutoSerialization 开启自动序列化，Set根据数据类型选择序列化方式并记录在数据中，Get使用记录的方式解析，
+// 读写两端无需再传入一致的序列化类型
+func WithAutoSerialization() Option {
+	return WithSerializer(AutoSerializer)
+}
+
+// WithSlidingExpiration 开启滑动过期，Get/GetBytes/GetWithLoad读取到未过期的数据后重新计算生命周期，
+// 持续被读取的数据不会过期
+func WithSlidingExpiration(sliding bool) Option {
+	return func(c *Config) {
+		c.SlidingExpiration = sliding
+	}
+}
+
+// WithEvictionPolicy 设置淘汰策略，lru/tinylfu按每条数据的key和value字节数计算内存，不超过HardMaxCacheSize
+func WithEvictionPolicy(p EvictionPolicy) Option {
+	return func(c *Config) {
+		c.EvictionPolicy = p
+	}
+}
+
+// WithAdmission 开启写入准入，key在窗口内被读取MinRequests次后Set/GetWithLoad才会保存，过滤只访问一次的数据
+// 写入本身不计入读取次数，Incr/CompareAndSwap等原子操作不受准入限制
+func WithAdmission(cfg AdmissionConfig) Option {
+	return func(c *Config) {
+		c.Admission = &cfg
+	}
+}
+
+// WithHotKeys 开启热点key统计，通过HotKeys(n)、admin命令/cmds/lc/hotkeys和定期上报的监控查看
+func WithHotKeys(cfg HotKeysConfig) Option {
+	return func(c *Config) {
+		c.HotKeys = &cfg
+	}
+}
+
+// WithMemoryWeight 设置内存预算后分配内存的权重，权重越大分到的内存越多，默认1
+func WithMemoryWeight(w float64) Option {
+	return func(c *Config) {
+		c.MemoryWeight = w
+	}
+}
+
+// WithMinMemory 设置内存预算后lru/tinylfu存储保证分配的内存，单位MB，计入静态预留
+func WithMinMemory(mb int) Option {
+	return func(c *Config) {
+		c.MinMemory = mb
+	}
+}
+
+// WithAutoSize 读取cgroup v1/v2内存限制，按比例设置HardMaxCacheSize，覆盖WithHardMaxCacheSize，未限制时不生效
+// 开启ShrinkOnPressure时堆内存持续超过阈值会淘汰最早的数据
+func WithAutoSize(cfg AutoSizeConfig) Option {
+	return func(c *Config) {
+		c.AutoSize = &cfg
+	}
+}
+
+// WithChunking 超过chunkSize字节的数据分块保存，突破bigcache单个分片大小的限制，任一分块被淘汰时视为不存在
+func WithChunking(chunkSize int) Option {
+	return func(c *Config) {
+		c.ChunkSize = chunkSize
+	}
+}
+
+// WithObjectMode 开启对象模式，直接保存Go对象不序列化，按maxObjects条数和HardMaxCacheSize估算的大小限制，超出时淘汰最早写入的数据
+// 读取返回同一个对象的指针或浅拷贝，写入后不能再修改对象，读取方也必须只读
+func WithObjectMode(maxObjects int) Option {
+	return func(c *Config) {
+		c.ObjectMode = true
+		c.MaxObjects = maxObjects
+	}
+}
+
+// WithClock 设置判断过期和驱动定期清理的时钟，单测中使用lctest.FakeClock可立即、确定地验证过期、兜底和刷新逻辑
+// 设置非系统时钟时bigcache(fifo)存储关闭内部基于真实时间的淘汰和清理，改为按该时钟每CleanWindow删除过期数据
+func WithClock(clock Clock) Option {
+	return func(c *Config) {
+		c.Clock = clock
+	}
+}
+
+// WithAccessTrace 按key采样记录读取、写入和删除(key hash、时间、数据大小)，异步写入文件，不记录原始key和数据
+// 记录的文件可使用lcsim重放，评估不同生命周期、内存上限和淘汰策略下的命中率和内存
+func WithAccessTrace(cfg AccessTraceConfig) Option {
+	return func(c *Config) {
+		c.AccessTrace = &cfg
+	}
+}
@@ -1,86 +1,176 @@
 package lc
 
 import (
+	"encoding"
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
+	"time"
 )
 
-// ToInterface 将string类型转化为bool、float、int、uint、error等interface对应类型
+var (
+	errorType           = reflect.TypeOf((*error)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// ToInterface 将string类型转化为to对应的类型，支持:
+// string、[]byte、bool、各宽度的int/uint/float(超出范围返回错误)、time.Duration、time.Time、error、
+// 实现了encoding.TextUnmarshaler的类型、基础类型的指针以及以基础类型定义的命名类型
 func ToInterface(s string, to interface{}) (interface{}, error) {
-	switch to.(type) {
-	case bool:
-		return strconv.ParseBool(s)
-	case float64:
-		return strconv.ParseFloat(s, 64)
-	case float32:
-		val, err := strconv.ParseFloat(s, 32)
-		return float32(val), err
-	case error:
+	if to == nil {
+		return nil, errors.New("lc: convert to nil type")
+	}
+	if _, ok := to.(error); ok {
 		return errors.New(s), nil
 	}
-	i, err := strconv.ParseInt(s, 10, 64)
-	if err != nil {
+	v := reflect.New(reflect.TypeOf(to))
+	if err := parseInto(s, v.Elem()); err != nil {
 		return nil, err
 	}
-	switch to.(type) {
-	case int:
-		return int(i), nil
-	case int64:
-		return int64(i), nil
-	case int32:
-		return int32(i), nil
-	case int16:
-		return int16(i), nil
-	case int8:
-		return int8(i), nil
-	case uint:
-		return uint(i), nil
-	case uint64:
-		return uint64(i), nil
-	case uint32:
-		return uint32(i), nil
-	case uint16:
-		return uint16(i), nil
-	case uint8:
-		return uint8(i), nil
+	return v.Elem().Interface(), nil
+}
+
+// FromString 将string类型转化后写入dst，dst必须是非nil指针，支持的类型同ToInterface
+func FromString(s string, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("lc: convert destination must be a non-nil pointer, got %T", dst)
+	}
+	return parseInto(s, v.Elem())
+}
+
+// parseInto 解析s并写入v，v必须可以Set
+func parseInto(s string, v reflect.Value) error {
+	t := v.Type()
+	switch {
+	case t == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case t == errorType:
+		v.Set(reflect.ValueOf(errors.New(s)))
+		return nil
+	case reflect.PtrTo(t).Implements(textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("lc: unsupported convert type %s", t)
+		}
+		v.SetBytes([]byte(s))
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return parseInto(s, v.Elem())
 	default:
-		return "", fmt.Errorf("Unspport type!")
+		return fmt.Errorf("lc: unsupported convert type %s", t)
 	}
+	return nil
 }
 
-// ToString 将bool、float、int、uint、error类型转化为string类型
+// ToString 将i转化为string类型，支持:
+// string、[]byte、bool、各宽度的int/uint/float、time.Duration、time.Time(RFC3339Nano)、error、
+// 实现了encoding.TextMarshaler的类型、基础类型的指针、以基础类型定义的命名类型以及fmt.Stringer
 func ToString(i interface{}) (string, error) {
 	switch s := i.(type) {
-	case bool:
-		return strconv.FormatBool(s), nil
-	case float64:
-		return strconv.FormatFloat(s, 'f', -1, 64), nil
-	case float32:
-		return strconv.FormatFloat(float64(s), 'f', -1, 32), nil
-	case int:
-		return strconv.Itoa(s), nil
-	case int64:
-		return strconv.FormatInt(s, 10), nil
-	case int32:
-		return strconv.Itoa(int(s)), nil
-	case int16:
-		return strconv.FormatInt(int64(s), 10), nil
-	case int8:
-		return strconv.FormatInt(int64(s), 10), nil
-	case uint:
-		return strconv.FormatInt(int64(s), 10), nil
-	case uint64:
-		return strconv.FormatInt(int64(s), 10), nil
-	case uint32:
-		return strconv.FormatInt(int64(s), 10), nil
-	case uint16:
-		return strconv.FormatInt(int64(s), 10), nil
-	case uint8:
-		return strconv.FormatInt(int64(s), 10), nil
+	case nil:
+		return "", errors.New("lc: unable to cast nil to string")
+	case string:
+		return s, nil
+	case []byte:
+		return string(s), nil
+	case time.Duration:
+		return s.String(), nil
+	case time.Time:
+		return s.Format(time.RFC3339Nano), nil
 	case error:
 		return s.Error(), nil
-	default:
-		return "", fmt.Errorf("unable to cast %#v of type %T to string", i, i)
+	case encoding.TextMarshaler:
+		b, err := s.MarshalText()
+		return string(b), err
+	}
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "", fmt.Errorf("lc: unable to cast nil %T to string", i)
+		}
+		return ToString(v.Elem().Interface())
+	}
+	if s, ok := i.(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+	return "", fmt.Errorf("lc: unable to cast %#v of type %T to string", i, i)
+}
+
+// isScalar 判断类型是否可以通过ToString/ToInterface与文本互相转化
+func isScalar(t reflect.Type) bool {
+	if t == durationType || t == errorType || t.Implements(errorType) {
+		return true
+	}
+	if t.Implements(textMarshalerType) {
+		if reflect.PtrTo(t).Implements(textUnmarshalerType) ||
+			(t.Kind() == reflect.Ptr && t.Implements(textUnmarshalerType)) {
+			return true
+		}
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	case reflect.Ptr:
+		return t.Elem().Kind() != reflect.Ptr && isScalar(t.Elem())
 	}
+	return false
 }
@@ -0,0 +1,94 @@
+package lc
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// testColor 测试用命名类型，实现了fmt.Stringer
+type testColor int
+
+func (c testColor) String() string { return "color" }
+
+// TestConvert 单测类型转换
+func TestConvert(t *testing.T) {
+	convey.Convey("TestConvert", t, func() {
+		convey.Convey("round trip", func() {
+			i64, u64, f32 := int64(-7), uint64(7), float32(0.1)
+			bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+			values := []interface{}{
+				"lc", []byte("lc"), true, false,
+				int(math.MinInt64), int8(math.MinInt8), int16(math.MaxInt16), int32(math.MinInt32), int64(math.MaxInt64),
+				uint(math.MaxUint64), uint8(math.MaxUint8), uint16(math.MaxUint16), uint32(math.MaxUint32),
+				uint64(math.MaxUint64), float32(math.MaxFloat32), float64(math.SmallestNonzeroFloat64), 0.1,
+				1500 * time.Millisecond, time.Date(2023, 5, 6, 7, 8, 9, 123456789, time.UTC),
+				net.ParseIP("127.0.0.1"), bigInt, testColor(3), &i64, &u64, &f32,
+			}
+			for _, v := range values {
+				s, err := ToString(v)
+				convey.So(err, convey.ShouldBeNil)
+				got, err := ToInterface(s, v)
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(got, convey.ShouldResemble, v)
+			}
+		})
+
+		convey.Convey("error round trip", func() {
+			s, err := ToString(errors.New("fail"))
+			convey.So(err, convey.ShouldBeNil)
+			got, err := ToInterface(s, errors.New(""))
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(got.(error).Error(), convey.ShouldEqual, "fail")
+			var e error
+			convey.So(FromString("fail", &e), convey.ShouldBeNil)
+			convey.So(e.Error(), convey.ShouldEqual, "fail")
+		})
+
+		convey.Convey("overflow", func() {
+			_, err := ToInterface("128", int8(0))
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = ToInterface("256", uint8(0))
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = ToInterface("-1", uint64(0))
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = ToInterface("1e39", float32(0))
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = ToInterface("18446744073709551616", uint64(0))
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+
+		convey.Convey("uint64 above MaxInt64", func() {
+			s, err := ToString(uint64(math.MaxUint64))
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(s, convey.ShouldEqual, "18446744073709551615")
+		})
+
+		convey.Convey("named type with Stringer formats underlying value", func() {
+			s, err := ToString(testColor(3))
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(s, convey.ShouldEqual, "3")
+		})
+
+		convey.Convey("unsupported", func() {
+			_, err := ToString(nil)
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = ToString((*int)(nil))
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = ToString(struct{}{})
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = ToInterface("1", struct{}{})
+			convey.So(err, convey.ShouldNotBeNil)
+			convey.So(FromString("1", 1), convey.ShouldNotBeNil)
+			convey.So(isScalar(reflect.TypeOf(struct{}{})), convey.ShouldBeFalse)
+			convey.So(isScalar(reflect.TypeOf(new(big.Int))), convey.ShouldBeTrue)
+			convey.So(isScalar(reflect.TypeOf(time.Time{})), convey.ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,90 @@
+package lc
+
+import (
+	"bytes"
+	"math"
+
+	"github.com/allegro/bigcache/v3"
+
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+// ErrNotInteger 数据不是整数或者计数溢出
+var ErrNotInteger = errs.New(2008, "lc: value is not an integer or out of range")
+
+// Incr 将key对应的整数加上delta并返回新值，key不存在或已过期时从0开始计数
+// 与同一个key上的其他写操作互斥，每次写入和Set一样重新开始计算生命周期
+func (c *Cache) Incr(key string, delta int64) (int64, error) {
+	unlock := c.locks.lock(key)
+	defer unlock()
+	var n int64
+	entry, ok, err := c.getAlive(key)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		if err := c.scalarSerializer().Unmarshal(entry, &n); err != nil {
+			return 0, ErrNotInteger
+		}
+	}
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		return 0, ErrNotInteger
+	}
+	n += delta
+	entry, err = c.scalarSerializer().Marshal(n)
+	if err != nil {
+		return 0, err
+	}
+	return n, c.write(key, entry)
+}
+
+// Decr 将key对应的整数减去delta并返回新值，语义同Incr
+func (c *Cache) Decr(key string, delta int64) (int64, error) {
+	if delta == math.MinInt64 {
+		return 0, ErrNotInteger
+	}
+	return c.Incr(key, -delta)
+}
+
+// CompareAndSwap 当key当前的值与old相同时写入newVal，old为nil表示key不存在或已过期
+// 比较基于cache序列化方式序列化后的数据
+func (c *Cache) CompareAndSwap(key string, old, newVal interface{}) (bool, error) {
+	s := c.serializer(nil, RawSerializer)
+	newEntry, err := s.Marshal(newVal)
+	if err != nil {
+		return false, err
+	}
+	var oldEntry []byte
+	if old != nil {
+		if oldEntry, err = s.Marshal(old); err != nil {
+			return false, err
+		}
+	}
+	unlock := c.locks.lock(key)
+	defer unlock()
+	entry, ok, err := c.getAlive(key)
+	if err != nil {
+		return false, err
+	}
+	if ok != (old != nil) || !bytes.Equal(entry, oldEntry) {
+		return false, nil
+	}
+	return true, c.write(key, newEntry)
+}
+
+// SetNX key不存在或已过期时写入val，返回是否写入
+func (c *Cache) SetNX(key string, val interface{}) (bool, error) {
+	return c.CompareAndSwap(key, nil, val)
+}
+
+// getAlive 获取未过期的数据，不存在或已过期时ok为false
+func (c *Cache) getAlive(key string) (entry []byte, ok bool, err error) {
+	h, entry, err := c.read(key)
+	if err == bigcache.ErrEntryNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return entry, c.entryStatus(h) != bigcache.Expired, nil
+}
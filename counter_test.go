@@ -0,0 +1,61 @@
+package lc
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestCounter 单测原子计数操作
+func TestCounter(t *testing.T) {
+	convey.Convey("TestCounter", t, func() {
+		cache := createCache("test-counter")
+		defer cache.Close()
+
+		convey.Convey("concurrent incr", func(c convey.C) {
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						_, err := cache.Incr("counter", 1)
+						c.So(err, convey.ShouldBeNil)
+					}
+				}()
+			}
+			wg.Wait()
+			n, err := cache.GetInt64("counter")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(n, convey.ShouldEqual, 5000)
+			n, err = cache.Decr("counter", 4000)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(n, convey.ShouldEqual, 1000)
+		})
+
+		convey.Convey("incr not integer", func() {
+			convey.So(cache.SetString("str", "abc"), convey.ShouldBeNil)
+			_, err := cache.Incr("str", 1)
+			convey.So(err, convey.ShouldEqual, ErrNotInteger)
+		})
+
+		convey.Convey("set nx and compare and swap", func() {
+			ok, err := cache.SetNX("nx", "a")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(ok, convey.ShouldBeTrue)
+			ok, err = cache.SetNX("nx", "b")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(ok, convey.ShouldBeFalse)
+
+			ok, err = cache.CompareAndSwap("nx", "b", "c")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(ok, convey.ShouldBeFalse)
+			ok, err = cache.CompareAndSwap("nx", "a", "c")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(ok, convey.ShouldBeTrue)
+			s, _ := cache.GetString("nx")
+			convey.So(s, convey.ShouldEqual, "c")
+		})
+	})
+}
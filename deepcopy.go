@@ -0,0 +1,100 @@
+package lc
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// protoMessageType proto.Message接口类型
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// deepCopyInto 将src深拷贝到dst，dst和src都必须是指针
+func deepCopyInto(dst, src interface{}) error {
+	dv, sv := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if dv.Kind() != reflect.Ptr || sv.Kind() != reflect.Ptr || dv.IsNil() {
+		return ErrTypeNotEqual
+	}
+	if !sv.Elem().Type().AssignableTo(dv.Elem().Type()) {
+		return ErrTypeNotEqual
+	}
+	if sv.IsNil() {
+		dv.Elem().Set(reflect.Zero(dv.Elem().Type()))
+		return nil
+	}
+	if m, ok := src.(proto.Message); ok {
+		if dm, ok := dst.(proto.Message); ok {
+			proto.Reset(dm)
+			proto.Merge(dm, m)
+			return nil
+		}
+	}
+	dv.Elem().Set(deepCopyValue(sv.Elem(), make(map[uintptr]reflect.Value)))
+	return nil
+}
+
+// deepCopyValue 递归拷贝值，visited记录已拷贝的指针，避免循环引用
+func deepCopyValue(src reflect.Value, visited map[uintptr]reflect.Value) reflect.Value {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			return src
+		}
+		if v, ok := visited[src.Pointer()]; ok {
+			return v
+		}
+		if src.Type().Implements(protoMessageType) {
+			v := reflect.ValueOf(proto.Clone(src.Interface().(proto.Message)))
+			visited[src.Pointer()] = v
+			return v
+		}
+		dst := reflect.New(src.Type().Elem())
+		visited[src.Pointer()] = dst
+		dst.Elem().Set(deepCopyValue(src.Elem(), visited))
+		return dst
+	case reflect.Interface:
+		if src.IsNil() {
+			return src
+		}
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(deepCopyValue(src.Elem(), visited))
+		return dst
+	case reflect.Struct:
+		dst := reflect.New(src.Type()).Elem()
+		// 先整体赋值以保留未导出字段，再逐个深拷贝导出字段
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				dst.Field(i).Set(deepCopyValue(src.Field(i), visited))
+			}
+		}
+		return dst
+	case reflect.Slice:
+		if src.IsNil() {
+			return src
+		}
+		dst := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(deepCopyValue(src.Index(i), visited))
+		}
+		return dst
+	case reflect.Array:
+		dst := reflect.New(src.Type()).Elem()
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(deepCopyValue(src.Index(i), visited))
+		}
+		return dst
+	case reflect.Map:
+		if src.IsNil() {
+			return src
+		}
+		dst := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			dst.SetMapIndex(deepCopyValue(iter.Key(), visited), deepCopyValue(iter.Value(), visited))
+		}
+		return dst
+	default:
+		return src
+	}
+}
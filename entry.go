@@ -0,0 +1,199 @@
+package lc
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+)
+
+// entryHeaderSize 数据头部长度: 1字节flags + 8字节写入时间(unix纳秒)
+const entryHeaderSize = 9
+
+// entryHeader 每条数据的头部信息
+type entryHeader struct {
+	flags     byte  // 标志位, 见flagChunked
+	timestamp int64 // 写入时间, unix纳秒, 滑动过期模式下为最近一次访问时间
+}
+
+// emptyHeader 预留头部空间，写入前由putHeader填充
+var emptyHeader [entryHeaderSize]byte
+
+// wrapEntry 在data前面加上头部
+func wrapEntry(h entryHeader, data []byte) []byte {
+	entry := make([]byte, entryHeaderSize+len(data))
+	putHeader(entry, h)
+	copy(entry[entryHeaderSize:], data)
+	return entry
+}
+
+// putHeader 将头部写入entry的前entryHeaderSize字节
+func putHeader(entry []byte, h entryHeader) {
+	entry[0] = h.flags
+	binary.LittleEndian.PutUint64(entry[1:entryHeaderSize], uint64(h.timestamp))
+}
+
+// unwrapEntry 解析头部，返回头部信息和数据
+func unwrapEntry(entry []byte) (entryHeader, []byte, error) {
+	if len(entry) < entryHeaderSize {
+		return entryHeader{}, nil, ErrInvalidEntry
+	}
+	h := entryHeader{
+		flags:     entry[0],
+		timestamp: int64(binary.LittleEndian.Uint64(entry[1:entryHeaderSize])),
+	}
+	return h, entry[entryHeaderSize:], nil
+}
+
+// now 当前时间
+func (c *Cache) now() time.Time {
+	return c.clock.Now()
+}
+
+// age 数据已存活的时间
+func (c *Cache) age(h entryHeader) time.Duration {
+	return c.now().Sub(time.Unix(0, h.timestamp))
+}
+
+// entryStatus 根据写入时间判断数据是否过期
+func (c *Cache) entryStatus(h entryHeader) bigcache.RemoveReason {
+	if c.age(h) >= c.lifeWindow {
+		return bigcache.Expired
+	}
+	return bigcache.RemoveReason(0)
+}
+
+// isExpired 判断带头部的数据是否过期，无法解析的数据视为过期
+func (c *Cache) isExpired(entry []byte) bool {
+	h, _, err := unwrapEntry(entry)
+	return err != nil || c.entryStatus(h) == bigcache.Expired
+}
+
+// read 读取数据并去掉头部，分块保存的数据拼接后返回，不存在或分块缺失返回bigcache.ErrEntryNotFound
+func (c *Cache) read(key string) (entryHeader, []byte, error) {
+	return c.readFrom(key, c.st.Get, c.getChunk)
+}
+
+// peek 读取并解析数据，不计入命中统计，不影响淘汰顺序
+func (c *Cache) peek(key string) (entryHeader, []byte, error) {
+	return c.readFrom(key, c.st.Peek, c.st.Peek)
+}
+
+// readFrom 使用get读取key对应的数据并解析，分块保存的数据使用getChunk读取所有分块后拼接
+func (c *Cache) readFrom(key string, get, getChunk func(string) ([]byte, error)) (entryHeader, []byte, error) {
+	entry, err := get(key)
+	if err != nil {
+		return entryHeader{}, nil, err
+	}
+	h, data, err := unwrapEntry(entry)
+	if err != nil || h.flags&flagChunked == 0 {
+		return h, data, err
+	}
+	data, err = c.readChunks(key, h, data, getChunk)
+	return h, data, err
+}
+
+// write 加上头部后写入数据，超过chunkSize时分块保存，调用方需持有key的分段锁
+func (c *Cache) write(key string, data []byte) error {
+	buf := getEntryBuf()
+	defer putEntryBuf(buf)
+	*buf = append(append((*buf)[:0], emptyHeader[:]...), data...)
+	return c.writeEntry(key, *buf)
+}
+
+// writeEntry 填充头部后写入，entry的前entryHeaderSize字节为预留的头部，调用方需持有key的分段锁
+func (c *Cache) writeEntry(key string, entry []byte) error {
+	return c.writeEntryAt(key, entry, c.now().UnixNano())
+}
+
+// writeEntryAt 以指定的写入时间写入，用于导入数据时保留剩余生命周期，调用方需持有key的分段锁
+func (c *Cache) writeEntryAt(key string, entry []byte, timestamp int64) error {
+	h := entryHeader{timestamp: timestamp}
+	old := c.chunkManifestOf(key)
+	if old != nil && old.timestamp == h.timestamp {
+		// 保证分块key与旧数据不同
+		h.timestamp++
+	}
+	var err error
+	if data := entry[entryHeaderSize:]; c.chunkSize > 0 && len(data) > c.chunkSize {
+		err = c.writeChunks(key, h, data)
+	} else {
+		putHeader(entry, h)
+		err = c.st.Set(key, entry)
+	}
+	if err == nil && old != nil {
+		c.deleteChunks(key, *old)
+	}
+	return err
+}
+
+// remove 删除数据及其分块，调用方需持有key的分段锁
+func (c *Cache) remove(key string) error {
+	if m := c.chunkManifestOf(key); m != nil {
+		c.deleteChunks(key, *m)
+	}
+	return c.st.Delete(key)
+}
+
+// touched 滑动过期模式下访问未过期的数据后重新写入，延长生命周期
+// 为避免热点key每次读取都写入，距上次写入不足1秒时不重新写入(bigcache时间精度为1秒)
+func (c *Cache) touched(key string, h entryHeader) {
+	if !c.slidingExpiration || c.age(h) < time.Second || c.entryStatus(h) == bigcache.Expired {
+		return
+	}
+	unlock := c.locks.lock(key)
+	defer unlock()
+	if c.objects != nil {
+		_ = c.objects.touchObject(key, h.timestamp, c.now().UnixNano())
+		return
+	}
+	// 加锁后重新读取，避免覆盖并发写入的新数据
+	cur, curData, err := c.read(key)
+	if err != nil || cur.timestamp != h.timestamp {
+		return
+	}
+	_ = c.write(key, curData)
+}
+
+// Touch 重新开始计算key的生命周期，key不存在返回ErrRecordNotFound
+func (c *Cache) Touch(key string) error {
+	unlock := c.locks.lock(key)
+	defer unlock()
+	if c.objects != nil {
+		if err := c.objects.touchObject(key, 0, c.now().UnixNano()); err != bigcache.ErrEntryNotFound {
+			return err
+		}
+		return ErrRecordNotFound
+	}
+	_, data, err := c.read(key)
+	if err == bigcache.ErrEntryNotFound {
+		return ErrRecordNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return c.write(key, data)
+}
+
+// TTL 返回key剩余的生命周期，已过期但未清除返回0，key不存在返回ErrRecordNotFound
+func (c *Cache) TTL(key string) (time.Duration, error) {
+	var (
+		h   entryHeader
+		err error
+	)
+	if c.objects != nil {
+		h, err = c.readObject(key, nil)
+	} else {
+		h, _, err = c.read(key)
+	}
+	if err == bigcache.ErrEntryNotFound {
+		return 0, ErrRecordNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if ttl := c.lifeWindow - c.age(h); ttl > 0 {
+		return ttl, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,46 @@
+package lc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestSlidingExpiration 单测滑动过期以及Touch/TTL
+func TestSlidingExpiration(t *testing.T) {
+	convey.Convey("TestSlidingExpiration", t, func() {
+		cache := createCache("test-sliding", WithLifeWindow(2*time.Second), WithSlidingExpiration(true))
+		defer cache.Close()
+
+		convey.So(cache.SetString("key", "v"), convey.ShouldBeNil)
+		convey.So(cache.SetString("other", "v"), convey.ShouldBeNil)
+		ttl, err := cache.TTL("key")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ttl, convey.ShouldBeBetween, time.Second, 2*time.Second)
+		_, err = cache.TTL("not-exist")
+		convey.So(err, convey.ShouldEqual, ErrRecordNotFound)
+
+		// 读取后重新计算生命周期
+		time.Sleep(1200 * time.Millisecond)
+		_, err = cache.GetString("key")
+		convey.So(err, convey.ShouldBeNil)
+		time.Sleep(1200 * time.Millisecond)
+		var v string
+		status, err := cache.GetWithEntryStatus("key", &v)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(status, convey.ShouldEqual, bigcache.RemoveReason(0))
+		status, err = cache.GetWithEntryStatus("other", &v)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(status, convey.ShouldEqual, bigcache.Expired)
+		ttl, _ = cache.TTL("other")
+		convey.So(ttl, convey.ShouldEqual, 0)
+
+		// 过期的数据不会因为读取而延长生命周期, Touch可以
+		convey.So(cache.Touch("other"), convey.ShouldBeNil)
+		ttl, _ = cache.TTL("other")
+		convey.So(ttl, convey.ShouldBeGreaterThan, time.Second)
+		convey.So(cache.Touch("not-exist"), convey.ShouldEqual, ErrRecordNotFound)
+	})
+}
@@ -0,0 +1,274 @@
+package lc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+)
+
+const (
+	// snapshotMagic 二进制快照文件头
+	snapshotMagic = "LCSNAP\x00\x01"
+	// maxSnapshotKeyLen 快照中key的最大长度，与bigcache保存key长度的上限一致
+	maxSnapshotKeyLen = 1<<16 - 1
+	// maxSnapshotSize 内存不限制时快照中单条数据的大小上限
+	maxSnapshotSize = 1 << 30
+)
+
+// JSONLEntry ExportJSONL/ImportJSONL每行的数据
+type JSONLEntry struct {
+	// Key key
+	Key string `json:"key"`
+	// Value 数据，JSON中为base64编码
+	Value []byte `json:"value"`
+	// Decoded decoder解析后的数据，只用于查看，导入时忽略
+	Decoded interface{} `json:"decoded,omitempty"`
+	// TTL 剩余生命周期，单位ms，已过期为0，导入时没有Time才按该值计算写入时间
+	TTL int64 `json:"ttl_ms"`
+	// Time 写入时间，滑动过期模式下为最近一次访问时间，导入时保留该时间
+	Time time.Time `json:"time"`
+	// Size 数据字节数
+	Size int `json:"size"`
+	// Requests 命中次数，需要开启StatsEnabled
+	Requests uint32 `json:"requests"`
+}
+
+// JSONLDecoder 导出时将数据解析为便于阅读的对象，如按序列化方式反序列化为结构体
+type JSONLDecoder func(key string, data []byte) (interface{}, error)
+
+// ExportJSONL 将cache中的数据按JSON Lines格式写入w，每行一个JSONLEntry，返回导出的条数
+// decoder不为nil时同时输出解析后的数据；包含已过期但未清除的数据；对象模式返回ErrObjectMode
+func (c *Cache) ExportJSONL(w io.Writer, decoder JSONLDecoder) (int, error) {
+	if c.objects != nil {
+		return 0, ErrObjectMode
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	n := 0
+	it := c.Iterator()
+	for it.SetNext() {
+		info, err := it.Value()
+		if err == bigcache.ErrEntryNotFound {
+			// 分块缺失的数据视为不存在
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		h := entryHeader{timestamp: info.timestamp}
+		stats := c.keyStats(info.Key(), h, info.Value(), c.st.KeyMetadata(info.Key()))
+		e := JSONLEntry{
+			Key:      info.Key(),
+			Value:    info.Value(),
+			TTL:      stats.TTL.Milliseconds(),
+			Time:     info.Time(),
+			Size:     stats.Size,
+			Requests: stats.Requests,
+		}
+		if decoder != nil {
+			if e.Decoded, err = decoder(e.Key, e.Value); err != nil {
+				return n, fmt.Errorf("lc: decode key %s: %w", e.Key, err)
+			}
+		}
+		if err := enc.Encode(&e); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, bw.Flush()
+}
+
+// ImportJSONL 读取ExportJSONL导出的数据写入cache，不受写入准入限制，返回导入的条数
+// 有time时保留原写入时间，没有time时按ttl_ms保留剩余生命周期
+func (c *Cache) ImportJSONL(r io.Reader) (int, error) {
+	if c.objects != nil {
+		return 0, ErrObjectMode
+	}
+	dec := json.NewDecoder(r)
+	n := 0
+	for {
+		var e JSONLEntry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("lc: import line %d: %w", n+1, err)
+		}
+		if err := c.importEntry(e.Key, e.Value, e.WriteTime(c.now(), c.lifeWindow).UnixNano()); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// WriteTime 导入时使用的写入时间，Time不为零时返回Time，否则按TTL计算: now - (lifeWindow - TTL)
+func (e *JSONLEntry) WriteTime(now time.Time, lifeWindow time.Duration) time.Time {
+	if !e.Time.IsZero() {
+		return e.Time
+	}
+	age := lifeWindow - time.Duration(e.TTL)*time.Millisecond
+	if age < 0 {
+		age = 0
+	}
+	return now.Add(-age)
+}
+
+// SnapshotEntry 二进制快照中的一条数据
+type SnapshotEntry struct {
+	// Key key
+	Key string
+	// Timestamp 写入时间(unix纳秒)
+	Timestamp int64
+	// Value 数据
+	Value []byte
+}
+
+// SnapshotWriter 按二进制快照格式逐条写入数据，可以不经过cache生成快照
+type SnapshotWriter struct {
+	bw  *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+// NewSnapshotWriter 创建快照写入器并写入文件头，写入完成后需要调用Flush
+func NewSnapshotWriter(w io.Writer) *SnapshotWriter {
+	sw := &SnapshotWriter{bw: bufio.NewWriter(w)}
+	_, _ = sw.bw.WriteString(snapshotMagic)
+	return sw
+}
+
+// Write 写入一条数据，格式为: uvarint key长度, key, varint写入时间(unix纳秒), uvarint数据长度, 数据
+func (sw *SnapshotWriter) Write(e SnapshotEntry) error {
+	_, _ = sw.bw.Write(sw.buf[:binary.PutUvarint(sw.buf[:], uint64(len(e.Key)))])
+	_, _ = sw.bw.WriteString(e.Key)
+	_, _ = sw.bw.Write(sw.buf[:binary.PutVarint(sw.buf[:], e.Timestamp)])
+	_, _ = sw.bw.Write(sw.buf[:binary.PutUvarint(sw.buf[:], uint64(len(e.Value)))])
+	_, err := sw.bw.Write(e.Value)
+	return err
+}
+
+// Flush 将缓冲的数据写入底层的io.Writer
+func (sw *SnapshotWriter) Flush() error {
+	return sw.bw.Flush()
+}
+
+// SnapshotReader 逐条读取二进制快照中的数据，可以不经过cache读取快照
+type SnapshotReader struct {
+	br      *bufio.Reader
+	maxSize uint64
+	started bool
+}
+
+// NewSnapshotReader 创建快照读取器，maxSize为单条数据的大小上限，0表示1GB
+func NewSnapshotReader(r io.Reader, maxSize uint64) *SnapshotReader {
+	if maxSize == 0 {
+		maxSize = maxSnapshotSize
+	}
+	return &SnapshotReader{br: bufio.NewReader(r), maxSize: maxSize}
+}
+
+// Next 读取下一条数据，没有更多数据时返回io.EOF
+// 文件头错误、数据不完整或key、数据长度超过上限时视为快照损坏，返回ErrInvalidEntry
+func (sr *SnapshotReader) Next() (SnapshotEntry, error) {
+	if !sr.started {
+		magic := make([]byte, len(snapshotMagic))
+		if _, err := io.ReadFull(sr.br, magic); err != nil || string(magic) != snapshotMagic {
+			return SnapshotEntry{}, ErrInvalidEntry
+		}
+		sr.started = true
+	}
+	keyLen, err := binary.ReadUvarint(sr.br)
+	if err == io.EOF {
+		return SnapshotEntry{}, io.EOF
+	}
+	if err != nil || keyLen > maxSnapshotKeyLen {
+		return SnapshotEntry{}, ErrInvalidEntry
+	}
+	key := make([]byte, keyLen)
+	if _, err := io.ReadFull(sr.br, key); err != nil {
+		return SnapshotEntry{}, ErrInvalidEntry
+	}
+	timestamp, err := binary.ReadVarint(sr.br)
+	if err != nil {
+		return SnapshotEntry{}, ErrInvalidEntry
+	}
+	size, err := binary.ReadUvarint(sr.br)
+	if err != nil || size > sr.maxSize {
+		return SnapshotEntry{}, ErrInvalidEntry
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(sr.br, data); err != nil {
+		return SnapshotEntry{}, ErrInvalidEntry
+	}
+	return SnapshotEntry{Key: string(key), Timestamp: timestamp, Value: data}, nil
+}
+
+// WriteSnapshot 将cache中的数据按二进制快照格式写入w，返回写入的条数，包含已过期但未清除的数据
+func (c *Cache) WriteSnapshot(w io.Writer) (int, error) {
+	if c.objects != nil {
+		return 0, ErrObjectMode
+	}
+	sw := NewSnapshotWriter(w)
+	n := 0
+	it := c.Iterator()
+	for it.SetNext() {
+		info, err := it.Value()
+		if err == bigcache.ErrEntryNotFound {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := sw.Write(SnapshotEntry{Key: info.Key(), Timestamp: info.timestamp, Value: info.Value()}); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, sw.Flush()
+}
+
+// ReadSnapshot 读取WriteSnapshot写入的快照并保存到cache，保留原写入时间，不受写入准入限制，返回读取的条数
+// key或数据长度超过上限时视为快照损坏，返回ErrInvalidEntry
+func (c *Cache) ReadSnapshot(r io.Reader) (int, error) {
+	if c.objects != nil {
+		return 0, ErrObjectMode
+	}
+	sr := NewSnapshotReader(r, c.snapshotSizeLimit())
+	n := 0
+	for {
+		e, err := sr.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := c.importEntry(e.Key, e.Value, e.Timestamp); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// snapshotSizeLimit 快照中单条数据的大小上限，超过cache内存上限的数据不可能保存
+func (c *Cache) snapshotSizeLimit() uint64 {
+	if c.quota.hardMaxCacheSize > 0 {
+		return uint64(c.quota.hardMaxCacheSize) * 1024 * 1024
+	}
+	return maxSnapshotSize
+}
+
+// importEntry 以指定的写入时间保存数据
+func (c *Cache) importEntry(key string, data []byte, timestamp int64) error {
+	buf := getEntryBuf()
+	defer putEntryBuf(buf)
+	*buf = append(append((*buf)[:0], emptyHeader[:]...), data...)
+	unlock := c.locks.lock(key)
+	defer unlock()
+	return c.writeEntryAt(key, *buf, timestamp)
+}
@@ -0,0 +1,120 @@
+package lc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestExportImport 单测JSONL和二进制快照的导出导入
+func TestExportImport(t *testing.T) {
+	convey.Convey("TestExportImport", t, func() {
+		src := createCache("test-export", WithShards(16), WithHardMaxCacheSize(64), WithLifeWindow(time.Minute),
+			WithChunking(16))
+		defer src.Close()
+		convey.So(src.Set("a", "hello"), convey.ShouldBeNil)
+		convey.So(src.Set("b", strings.Repeat("x", 100)), convey.ShouldBeNil)
+
+		// JSONL
+		var out bytes.Buffer
+		n, err := src.ExportJSONL(&out, func(key string, data []byte) (interface{}, error) {
+			return string(data), nil
+		})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, 2)
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		convey.So(len(lines), convey.ShouldEqual, 2)
+		var e JSONLEntry
+		convey.So(json.Unmarshal([]byte(lines[0]), &e), convey.ShouldBeNil)
+		convey.So(e.Decoded, convey.ShouldEqual, string(e.Value))
+		convey.So(e.TTL, convey.ShouldBeGreaterThan, 50*1000)
+
+		dst := createCache("test-import", WithShards(16), WithHardMaxCacheSize(64), WithLifeWindow(time.Minute),
+			WithEvictionPolicy(EvictionLRU))
+		defer dst.Close()
+		n, err = dst.ImportJSONL(&out)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, 2)
+		var v string
+		convey.So(dst.Get("b", &v), convey.ShouldBeNil)
+		convey.So(v, convey.ShouldEqual, strings.Repeat("x", 100))
+		ttl, err := dst.TTL("a")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ttl, convey.ShouldBeGreaterThan, 50*time.Second)
+		convey.So(ttl, convey.ShouldBeLessThanOrEqualTo, time.Minute)
+
+		// 过期的数据导入后仍为过期
+		expired := `{"key":"c","value":"Yw==","ttl_ms":0}`
+		n, err = dst.ImportJSONL(strings.NewReader(expired))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, 1)
+		status, err := dst.GetWithEntryStatus("c", nil)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(status, convey.ShouldNotEqual, 0)
+		// 有time时保留原写入时间，忽略ttl_ms
+		timed, _ := json.Marshal(JSONLEntry{Key: "d", Value: []byte("d"), TTL: 60 * 1000, Time: time.Now().Add(-50 * time.Second)})
+		_, err = dst.ImportJSONL(bytes.NewReader(timed))
+		convey.So(err, convey.ShouldBeNil)
+		ttl, err = dst.TTL("d")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ttl, convey.ShouldBeLessThanOrEqualTo, 10*time.Second)
+		convey.So(ttl, convey.ShouldBeGreaterThan, 5*time.Second)
+		_, err = dst.ImportJSONL(strings.NewReader("{bad"))
+		convey.So(err, convey.ShouldNotBeNil)
+
+		// 二进制快照保留写入时间
+		var snap bytes.Buffer
+		n, err = src.WriteSnapshot(&snap)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, 2)
+		restored := createCache("test-snapshot", WithShards(16), WithHardMaxCacheSize(64), WithLifeWindow(time.Minute))
+		defer restored.Close()
+		n, err = restored.ReadSnapshot(bytes.NewReader(snap.Bytes()))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, 2)
+		convey.So(restored.Get("a", &v), convey.ShouldBeNil)
+		convey.So(v, convey.ShouldEqual, "hello")
+		want, _ := src.TTL("a")
+		got, _ := restored.TTL("a")
+		convey.So(want-got, convey.ShouldBeLessThan, time.Second)
+
+		// 不经过cache逐条读写快照
+		sr := NewSnapshotReader(bytes.NewReader(snap.Bytes()), 0)
+		var copied bytes.Buffer
+		sw := NewSnapshotWriter(&copied)
+		for {
+			e, err := sr.Next()
+			if err == io.EOF {
+				break
+			}
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(sw.Write(e), convey.ShouldBeNil)
+		}
+		convey.So(sw.Flush(), convey.ShouldBeNil)
+		convey.So(copied.Bytes(), convey.ShouldResemble, snap.Bytes())
+
+		_, err = restored.ReadSnapshot(strings.NewReader("bad"))
+		convey.So(err, convey.ShouldEqual, ErrInvalidEntry)
+		_, err = restored.ReadSnapshot(bytes.NewReader(snap.Bytes()[:snap.Len()-1]))
+		convey.So(err, convey.ShouldEqual, ErrInvalidEntry)
+		// 长度损坏时不按长度分配内存
+		var b [binary.MaxVarintLen64]byte
+		corrupt := bytes.NewBufferString(snapshotMagic)
+		corrupt.Write(b[:binary.PutUvarint(b[:], 1<<40)])
+		_, err = restored.ReadSnapshot(corrupt)
+		convey.So(err, convey.ShouldEqual, ErrInvalidEntry)
+		corrupt = bytes.NewBufferString(snapshotMagic)
+		corrupt.Write(b[:binary.PutUvarint(b[:], 1)])
+		corrupt.WriteString("k")
+		corrupt.Write(b[:binary.PutVarint(b[:], 0)])
+		corrupt.Write(b[:binary.PutUvarint(b[:], 65<<20)])
+		_, err = restored.ReadSnapshot(corrupt)
+		convey.So(err, convey.ShouldEqual, ErrInvalidEntry)
+	})
+}
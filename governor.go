@@ -0,0 +1,267 @@
+package lc
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/log"
+)
+
+// ErrMemoryBudgetExceeded 注册cache后静态预留的内存超过进程内存预算
+var ErrMemoryBudgetExceeded = errs.New(2010, "lc: memory budget exceeded")
+
+// MemoryBudget 进程内所有cache共享的内存预算
+type MemoryBudget struct {
+	// MaxMemory 所有cache的内存上限，单位MB，<=0表示不限制
+	MaxMemory int `yaml:"max_memory"`
+	// RebalanceInterval 按命中情况重新分配内存的周期，默认1分钟
+	RebalanceInterval time.Duration `yaml:"rebalance_interval"`
+}
+
+// minElasticMemory lru/tinylfu存储未设置MinMemory时保证分配的内存，计入静态预留，避免剩余内存为0时容量被设为0
+const minElasticMemory = 1024 * 1024
+
+// memoryQuota cache参与内存预算的配置
+type memoryQuota struct {
+	weight           float64
+	minMemory        int // MB
+	hardMaxCacheSize int // MB
+}
+
+// newMemoryQuota 从配置中获取参与内存预算的配置
+func newMemoryQuota(cfg *Config) memoryQuota {
+	return memoryQuota{weight: cfg.MemoryWeight, minMemory: cfg.MinMemory, hardMaxCacheSize: cfg.HardMaxCacheSize}
+}
+
+// governorMember 受内存预算管理的cache
+type governorMember struct {
+	cache    *Cache
+	elastic  *memStore // lru/tinylfu存储可调整容量, fifo(bigcache)为nil
+	reserved int       // 静态预留字节数: fifo为HardMaxCacheSize, lru/tinylfu为MinMemory(至少minElasticMemory)
+	maxCost  int       // lru/tinylfu的容量上限, 0表示不限制
+	weight   float64
+	lastHits int64
+}
+
+// memoryGovernor 内存预算管理
+// fifo(bigcache)存储无法调整容量，按HardMaxCacheSize静态预留；lru/tinylfu存储先保证MinMemory，
+// 剩余内存按权重和上个周期的命中数分配，命中多的cache获得更多内存
+type memoryGovernor struct {
+	mu       sync.Mutex
+	budget   int // 字节数
+	interval time.Duration
+	members  map[*Cache]*governorMember
+	done     chan struct{}
+}
+
+// governor 全局内存预算，nil表示不限制
+var governor struct {
+	sync.Mutex
+	g *memoryGovernor
+}
+
+// SetMemoryBudget 设置进程内所有cache共享的内存预算，建议在注册cache之前调用
+// 已注册和GetCache已自动创建的cache同样加入预算管理，静态预留超过预算时返回ErrMemoryBudgetExceeded，原有的预算不变；
+// 设置后GetCache自动创建的cache使用LRU淘汰策略以便调整容量
+func SetMemoryBudget(b MemoryBudget) error {
+	governor.Lock()
+	defer governor.Unlock()
+	if b.MaxMemory <= 0 {
+		if governor.g != nil {
+			close(governor.g.done)
+			governor.g = nil
+		}
+		return nil
+	}
+	if b.RebalanceInterval <= 0 {
+		b.RebalanceInterval = time.Minute
+	}
+	g := &memoryGovernor{
+		budget:   b.MaxMemory * 1024 * 1024,
+		interval: b.RebalanceInterval,
+		members:  make(map[*Cache]*governorMember),
+		done:     make(chan struct{}),
+	}
+	if old := governor.g; old != nil {
+		old.mu.Lock()
+		for c, m := range old.members {
+			g.members[c] = m
+		}
+		old.mu.Unlock()
+	}
+	cachePool.RLock()
+	for _, c := range cachePool.m {
+		if _, ok := g.members[c]; ok || c.closed() {
+			continue
+		}
+		m, err := newGovernorMember(c)
+		if err != nil {
+			cachePool.RUnlock()
+			return err
+		}
+		g.members[c] = m
+	}
+	cachePool.RUnlock()
+	if reserved := g.reserved(); reserved > g.budget {
+		return fmt.Errorf("%w: reserved %dMB, budget %dMB", ErrMemoryBudgetExceeded,
+			reserved/(1024*1024), b.MaxMemory)
+	}
+	if governor.g != nil {
+		close(governor.g.done)
+	}
+	governor.g = g
+	g.rebalance()
+	go g.run()
+	return nil
+}
+
+// currentGovernor 当前的内存预算管理，未设置返回nil
+func currentGovernor() *memoryGovernor {
+	governor.Lock()
+	defer governor.Unlock()
+	return governor.g
+}
+
+// newGovernorMember 按cache的配置计算静态预留和容量上限，fifo存储不限制大小时返回ErrMemoryBudgetExceeded
+func newGovernorMember(c *Cache) (*governorMember, error) {
+	q := c.quota
+	m := &governorMember{cache: c, weight: q.weight}
+	if m.weight <= 0 {
+		m.weight = 1
+	}
+	if ms, ok := c.st.(*memStore); ok {
+		m.elastic = ms
+		m.reserved = q.minMemory * 1024 * 1024
+		if m.reserved < minElasticMemory {
+			m.reserved = minElasticMemory
+		}
+		m.maxCost = q.hardMaxCacheSize * 1024 * 1024
+		if m.maxCost > 0 && m.maxCost < m.reserved {
+			m.maxCost = m.reserved
+		}
+		return m, nil
+	}
+	if q.hardMaxCacheSize <= 0 {
+		return nil, fmt.Errorf("%w: cache %s has unlimited size", ErrMemoryBudgetExceeded, c.name)
+	}
+	m.reserved = q.hardMaxCacheSize * 1024 * 1024
+	return m, nil
+}
+
+// join 将cache加入内存预算管理，静态预留超过预算时返回ErrMemoryBudgetExceeded
+func (g *memoryGovernor) join(c *Cache) error {
+	if g == nil {
+		return nil
+	}
+	m, err := newGovernorMember(c)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if reserved := g.reservedLocked() + m.reserved; reserved > g.budget {
+		return fmt.Errorf("%w: cache %s, reserved %dMB, budget %dMB", ErrMemoryBudgetExceeded, c.name,
+			reserved/(1024*1024), g.budget/(1024*1024))
+	}
+	g.members[c] = m
+	g.rebalanceLocked()
+	return nil
+}
+
+// leave cache关闭后退出内存预算管理
+func (g *memoryGovernor) leave(c *Cache) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.members[c]; ok {
+		delete(g.members, c)
+		g.rebalanceLocked()
+	}
+}
+
+// run 定期重新分配内存
+func (g *memoryGovernor) run() {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			g.rebalance()
+		case <-g.done:
+			return
+		}
+	}
+}
+
+// reserved 所有cache静态预留的字节数
+func (g *memoryGovernor) reserved() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.reservedLocked()
+}
+
+// reservedLocked 所有cache静态预留的字节数，调用方需持有锁
+func (g *memoryGovernor) reservedLocked() int {
+	n := 0
+	for _, m := range g.members {
+		n += m.reserved
+	}
+	return n
+}
+
+// rebalance 重新分配内存
+func (g *memoryGovernor) rebalance() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.rebalanceLocked()
+}
+
+// rebalanceLocked 将静态预留之外的内存按权重*(上个周期命中数+1)分配给lru/tinylfu存储，调用方需持有锁
+// 每个lru/tinylfu存储至少分配静态预留的内存，达到容量上限的cache多出的部分分给其他cache
+func (g *memoryGovernor) rebalanceLocked() {
+	pool := g.budget - g.reservedLocked()
+	scores := make(map[*governorMember]float64)
+	alloc := make(map[*governorMember]int)
+	for _, m := range g.members {
+		if m.elastic == nil {
+			continue
+		}
+		hits := m.cache.st.Stats().Hits
+		scores[m] = m.weight * float64(hits-m.lastHits+1)
+		m.lastHits = hits
+		alloc[m] = m.reserved
+	}
+	for pool > 0 && len(scores) > 0 {
+		total := 0.0
+		for _, s := range scores {
+			total += s
+		}
+		capped, left := false, pool
+		for m, s := range scores {
+			share := int(float64(pool) * s / total)
+			if m.maxCost > 0 && alloc[m]+share >= m.maxCost {
+				share = m.maxCost - alloc[m]
+				delete(scores, m)
+				capped = true
+			}
+			alloc[m] += share
+			left -= share
+		}
+		// 没有cache达到上限时分配结束，否则将多出的部分继续分给其他cache
+		if !capped {
+			break
+		}
+		pool = left
+	}
+	for m, n := range alloc {
+		if n != m.elastic.maxCost() {
+			log.Debugf("lc: rebalance memory, name:%v, from:%vMB, to:%vMB", m.cache.name,
+				m.elastic.maxCost()/(1024*1024), n/(1024*1024))
+			m.elastic.setMaxCost(n)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package lc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestMemoryGovernor 单测全局内存预算
+func TestMemoryGovernor(t *testing.T) {
+	convey.Convey("TestMemoryGovernor", t, func() {
+		// 使用独立的cachePool，避免其他单测注册的cache计入预算
+		cachePool.Lock()
+		pool := cachePool.m
+		cachePool.m = make(map[string]*Cache)
+		cachePool.Unlock()
+		defer func() {
+			cachePool.Lock()
+			cachePool.m = pool
+			cachePool.Unlock()
+		}()
+		convey.So(SetMemoryBudget(MemoryBudget{MaxMemory: 16}), convey.ShouldBeNil)
+		defer func() { _ = SetMemoryBudget(MemoryBudget{}) }()
+
+		hot := createCache("test-governor-hot", WithShards(1), WithEvictionPolicy(EvictionLRU), WithMinMemory(2))
+		defer hot.Close()
+		cold := createCache("test-governor-cold", WithShards(1), WithEvictionPolicy(EvictionTinyLFU),
+			WithMemoryWeight(3))
+		defer cold.Close()
+		fixed := createCache("test-governor-fixed", WithHardMaxCacheSize(4))
+		defer fixed.Close()
+		maxCost := func(c *Cache) int { return c.st.(*memStore).maxCost() / (1024 * 1024) }
+		// 16MB - 4MB(fifo) - 2MB(min) - 1MB(最小分配) = 9MB 按权重1:3分配
+		convey.So(maxCost(hot), convey.ShouldEqual, 2+2)
+		convey.So(maxCost(cold), convey.ShouldEqual, 7)
+
+		// 静态预留超过预算
+		convey.So(func() { createCache("test-governor-big", WithHardMaxCacheSize(16)) }, convey.ShouldPanic)
+		convey.So(func() {
+			createCache("test-governor-min", WithEvictionPolicy(EvictionLRU), WithMinMemory(11))
+		}, convey.ShouldPanic)
+		convey.So(SetMemoryBudget(MemoryBudget{MaxMemory: 5}), convey.ShouldNotBeNil)
+
+		// 命中多的cache分到更多内存，缩容时淘汰超出的数据
+		value := make([]byte, 512*1024)
+		for i := 0; i < 8; i++ {
+			convey.So(cold.Set(fmt.Sprintf("k%d", i), value), convey.ShouldBeNil)
+		}
+		convey.So(hot.Set("k", "v"), convey.ShouldBeNil)
+		for i := 0; i < 1000; i++ {
+			_, _ = hot.GetBytes("k")
+		}
+		currentGovernor().rebalance()
+		convey.So(maxCost(hot), convey.ShouldBeGreaterThanOrEqualTo, 10)
+		convey.So(cold.Capacity(), convey.ShouldBeLessThanOrEqualTo, cold.st.(*memStore).maxCost())
+
+		// cache关闭后释放内存
+		hot.Close()
+		currentGovernor().rebalance()
+		convey.So(maxCost(cold), convey.ShouldEqual, 12)
+
+		// 设置预算时已注册和GetCache自动创建的cache同样加入管理，静态预留超过预算时拒绝
+		convey.So(SetMemoryBudget(MemoryBudget{}), convey.ShouldBeNil)
+		auto := GetCache("test-governor-auto")
+		convey.So(SetMemoryBudget(MemoryBudget{MaxMemory: 16}), convey.ShouldNotBeNil)
+		convey.So(currentGovernor(), convey.ShouldBeNil)
+		auto.Close()
+		RegisterCache("test-governor-registered", WithHardMaxCacheSize(8))
+		RegisterCache("test-governor-lru", WithShards(1), WithEvictionPolicy(EvictionLRU))
+		registered, lru := GetCache("test-governor-registered"), GetCache("test-governor-lru")
+		defer registered.Close()
+		defer lru.Close()
+		// 剩余内存为0时lru存储仍保留最小分配
+		convey.So(SetMemoryBudget(MemoryBudget{MaxMemory: 9}), convey.ShouldBeNil)
+		convey.So(currentGovernor().reserved(), convey.ShouldEqual, 9*1024*1024)
+		convey.So(maxCost(lru), convey.ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,214 @@
+package lc
+
+import (
+	"container/heap"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"trpc.group/trpc-go/trpc-go/log"
+	"trpc.group/trpc-go/trpc-go/metrics"
+)
+
+// hotKeysReportTopN 定期上报的热点key个数
+const hotKeysReportTopN = 10
+
+// HotKeysConfig 热点key统计配置
+type HotKeysConfig struct {
+	// Capacity 跟踪的key个数，越大结果越准确，默认100
+	Capacity int `yaml:"capacity"`
+	// ReportInterval 上报周期，每次上报后计数减半，使结果反映最近的访问，<=0表示不上报也不衰减
+	ReportInterval time.Duration `yaml:"report_interval"`
+	// ReportRawKeys 上报原始key，默认上报key的hash，避免泄露用户标识和监控维度无限增长
+	ReportRawKeys bool `yaml:"report_raw_keys"`
+}
+
+// HotKey 热点key
+type HotKey struct {
+	// Key 热点key
+	Key string `json:"key"`
+	// Count 估算的读取次数，不小于实际次数
+	Count int64 `json:"count"`
+	// Error 估算误差上限，实际次数不小于Count-Error
+	Error int64 `json:"error"`
+}
+
+// hotKeyItem space-saving计数器
+type hotKeyItem struct {
+	HotKey
+	index int // 在堆中的下标
+}
+
+// hotKeyHeap 按Count排序的小顶堆
+type hotKeyHeap []*hotKeyItem
+
+func (h hotKeyHeap) Len() int           { return len(h) }
+func (h hotKeyHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h hotKeyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+// Push heap.Interface
+func (h *hotKeyHeap) Push(x interface{}) {
+	item := x.(*hotKeyItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+// Pop heap.Interface
+func (h *hotKeyHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// hotKeysMinStripeCapacity 每个分段最少跟踪的key个数，避免分段过多时单个分段跟踪的key太少
+const hotKeysMinStripeCapacity = 8
+
+// hotKeys 基于space-saving算法统计读取次数最多的key，只跟踪固定个数的key
+// 按key hash分段，每段独立加锁并跟踪capacity/分段数个key，读取只锁定所在的分段
+type hotKeys struct {
+	mask    uint64
+	stripes []hotKeyStripe
+	rawKeys bool // 上报和admin命令返回原始key
+}
+
+// hotKeyStripe 热点key统计的一个分段，计数器已满时新key替换计数最小的key，并继承其计数作为误差
+type hotKeyStripe struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*hotKeyItem
+	heap     hotKeyHeap
+}
+
+// newHotKeys 创建热点key统计，分段数不超过stripes且每段至少跟踪hotKeysMinStripeCapacity个key
+func newHotKeys(capacity, stripes int) *hotKeys {
+	if capacity <= 0 {
+		capacity = 100
+	}
+	n := 1
+	for n*2 <= stripes && n*2*hotKeysMinStripeCapacity <= capacity {
+		n *= 2
+	}
+	h := &hotKeys{mask: uint64(n - 1), stripes: make([]hotKeyStripe, n)}
+	for i := range h.stripes {
+		c := (capacity + n - 1) / n
+		h.stripes[i] = hotKeyStripe{capacity: c, items: make(map[string]*hotKeyItem, c)}
+	}
+	return h
+}
+
+// record 记录一次读取
+func (h *hotKeys) record(key string) {
+	if h == nil {
+		return
+	}
+	h.stripes[hashKey(key)&h.mask].record(key)
+}
+
+// record 在分段中记录一次读取
+func (s *hotKeyStripe) record(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if item, ok := s.items[key]; ok {
+		item.Count++
+		heap.Fix(&s.heap, item.index)
+		return
+	}
+	if len(s.heap) < s.capacity {
+		item := &hotKeyItem{HotKey: HotKey{Key: key, Count: 1}}
+		s.items[key] = item
+		heap.Push(&s.heap, item)
+		return
+	}
+	min := s.heap[0]
+	delete(s.items, min.Key)
+	min.Key, min.Error = key, min.Count
+	min.Count++
+	s.items[key] = min
+	heap.Fix(&s.heap, 0)
+}
+
+// top 返回读取次数最多的n个key，按次数从大到小排序
+func (h *hotKeys) top(n int) []HotKey {
+	if h == nil {
+		return nil
+	}
+	var keys []HotKey
+	for i := range h.stripes {
+		s := &h.stripes[i]
+		s.mu.Lock()
+		for _, item := range s.heap {
+			keys = append(keys, item.HotKey)
+		}
+		s.mu.Unlock()
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Count > keys[j].Count })
+	if n >= 0 && n < len(keys) {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// decay 计数减半
+func (h *hotKeys) decay() {
+	for i := range h.stripes {
+		s := &h.stripes[i]
+		s.mu.Lock()
+		for _, item := range s.heap {
+			item.Count >>= 1
+			item.Error >>= 1
+		}
+		s.mu.Unlock()
+	}
+}
+
+// HotKeys 返回读取次数最多的n个key，n<0返回所有跟踪的key，未开启热点key统计返回nil
+func (c *Cache) HotKeys(n int) []HotKey {
+	return c.hotKeys.top(n)
+}
+
+// exportKey 上报和admin命令返回的key，未开启ReportRawKeys时返回key的hash
+func (h *hotKeys) exportKey(key string) string {
+	if h.rawKeys {
+		return key
+	}
+	return keyHash(key)
+}
+
+// exportHotKeys 返回读取次数最多的n个key，用于admin命令，未开启ReportRawKeys时key替换为hash
+func (c *Cache) exportHotKeys(n int) []HotKey {
+	keys := c.hotKeys.top(n)
+	for i := range keys {
+		keys[i].Key = c.hotKeys.exportKey(keys[i].Key)
+	}
+	return keys
+}
+
+// reportHotKeys 定期上报热点key，每次上报后计数减半，未开启ReportRawKeys时上报key的hash
+func (c *Cache) reportHotKeys(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for i, k := range c.exportHotKeys(hotKeysReportTopN) {
+				dimension := []*metrics.Dimension{
+					{Name: "CacheName", Value: c.name},
+					{Name: "Rank", Value: strconv.Itoa(i + 1)},
+					{Name: "Key", Value: k.Key},
+				}
+				metric := []*metrics.Metrics{metrics.NewMetrics("hot-key-count", float64(k.Count), metrics.PolicySET)}
+				if err := metrics.ReportMultiDimensionMetricsX("lc_hot_keys", dimension, metric); err != nil {
+					log.Errorf("lc: report hot keys failed, name:%v, err:%v", c.name, err)
+				}
+			}
+			c.hotKeys.decay()
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package lc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestHotKeys 单测热点key统计
+func TestHotKeys(t *testing.T) {
+	convey.Convey("TestHotKeys", t, func() {
+		RegisterCache("test-hotkeys", WithHotKeys(HotKeysConfig{Capacity: 10}))
+		cache := GetCache("test-hotkeys")
+		convey.So(cache.SetString("hot", "v"), convey.ShouldBeNil)
+		for i := 0; i < 100; i++ {
+			_, _ = cache.GetString("hot")
+			_, _ = cache.GetString(fmt.Sprintf("cold%d", i))
+			if i%2 == 0 {
+				_, _ = cache.GetBytes("warm")
+			}
+		}
+		keys := cache.HotKeys(2)
+		convey.So(len(keys), convey.ShouldEqual, 2)
+		convey.So(keys[0].Key, convey.ShouldEqual, "hot")
+		convey.So(keys[0].Count, convey.ShouldBeGreaterThanOrEqualTo, 100)
+		convey.So(keys[1].Key, convey.ShouldEqual, "warm")
+		convey.So(keys[1].Count-keys[1].Error, convey.ShouldBeLessThanOrEqualTo, 50)
+		convey.So(len(cache.HotKeys(-1)), convey.ShouldEqual, 10)
+		convey.So(createCache("test-no-hotkeys").HotKeys(10), convey.ShouldBeNil)
+
+		w := httptest.NewRecorder()
+		hotKeysHandler(w, httptest.NewRequest("GET", "/cmds/lc/hotkeys?name=test-hotkeys&n=1", nil))
+		var rsp struct {
+			ErrorCode int                 `json:"errorcode"`
+			Caches    map[string][]HotKey `json:"caches"`
+		}
+		convey.So(json.Unmarshal(w.Body.Bytes(), &rsp), convey.ShouldBeNil)
+		convey.So(rsp.ErrorCode, convey.ShouldEqual, 0)
+		convey.So(rsp.Caches["test-hotkeys"], convey.ShouldResemble, []HotKey{{Key: keyHash("hot"), Count: keys[0].Count}})
+
+		RegisterCache("test-hotkeys-raw", WithHotKeys(HotKeysConfig{Capacity: 10, ReportRawKeys: true}))
+		_, _ = GetCache("test-hotkeys-raw").GetString("hot")
+		w = httptest.NewRecorder()
+		hotKeysHandler(w, httptest.NewRequest("GET", "/cmds/lc/hotkeys?name=test-hotkeys-raw", nil))
+		convey.So(json.Unmarshal(w.Body.Bytes(), &rsp), convey.ShouldBeNil)
+		convey.So(rsp.Caches["test-hotkeys-raw"], convey.ShouldResemble, []HotKey{{Key: "hot", Count: 1}})
+	})
+}
+
+// TestHotKeysStripes 单测分段的热点key统计
+func TestHotKeysStripes(t *testing.T) {
+	convey.Convey("TestHotKeysStripes", t, func() {
+		h := newHotKeys(100, 128)
+		convey.So(len(h.stripes), convey.ShouldEqual, 8)
+		convey.So(h.stripes[0].capacity, convey.ShouldEqual, 13)
+		convey.So(len(newHotKeys(10, 128).stripes), convey.ShouldEqual, 1)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					h.record(fmt.Sprintf("hot%d", j%4))
+					h.record(fmt.Sprintf("cold%d-%d", i, j))
+				}
+			}(i)
+		}
+		wg.Wait()
+		keys := h.top(4)
+		convey.So(len(keys), convey.ShouldEqual, 4)
+		for _, k := range keys {
+			convey.So(k.Key, convey.ShouldStartWith, "hot")
+			convey.So(k.Count, convey.ShouldBeGreaterThanOrEqualTo, 2000)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package lc
+
+import "time"
+
+// EntryInfo 迭代器返回的数据信息
+type EntryInfo struct {
+	key       string
+	hash      uint64
+	timestamp int64
+	value     []byte
+}
+
+// Key 返回key
+func (e EntryInfo) Key() string {
+	return e.key
+}
+
+// Hash 返回key的hash值
+func (e EntryInfo) Hash() uint64 {
+	return e.hash
+}
+
+// Timestamp 返回写入时间(unix秒)，滑动过期模式下为最近一次访问时间
+func (e EntryInfo) Timestamp() uint64 {
+	return uint64(time.Unix(0, e.timestamp).Unix())
+}
+
+// Time 返回写入时间，滑动过期模式下为最近一次访问时间
+func (e EntryInfo) Time() time.Time {
+	return time.Unix(0, e.timestamp)
+}
+
+// Value 返回数据
+func (e EntryInfo) Value() []byte {
+	return e.value
+}
+
+// EntryIterator 遍历cache的迭代器，用法与bigcache.EntryInfoIterator一致
+// 分块保存的数据只返回一次，Value为拼接后的数据
+type EntryIterator struct {
+	it storeIterator
+	c  *Cache
+}
+
+// SetNext 移动到下一条数据，跳过分块，没有更多数据时返回false
+func (it *EntryIterator) SetNext() bool {
+	for it.it.SetNext() {
+		if key, _, _, err := it.it.Value(); err != nil || !isChunkKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value 返回当前数据
+func (it *EntryIterator) Value() (EntryInfo, error) {
+	key, hash, entry, err := it.it.Value()
+	if err != nil {
+		return EntryInfo{}, err
+	}
+	h, data, err := unwrapEntry(entry)
+	if err != nil {
+		return EntryInfo{}, err
+	}
+	if h.flags&flagChunked != 0 {
+		if data, err = it.c.readChunks(key, h, data, it.c.st.Peek); err != nil {
+			return EntryInfo{}, err
+		}
+	}
+	return EntryInfo{key: key, hash: hash, timestamp: h.timestamp, value: data}, nil
+}
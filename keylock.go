@@ -0,0 +1,40 @@
+package lc
+
+import "sync"
+
+// fnv64a常量，与bigcache默认hash算法一致
+const (
+	offset64 = 14695981039346656037
+	prime64  = 1099511628211
+)
+
+// hashKey 计算key的fnv64a hash，与bigcache分片使用的hash一致
+func hashKey(key string) uint64 {
+	var hash uint64 = offset64
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= prime64
+	}
+	return hash
+}
+
+// keyLocks 按key分段的锁，分段数与bigcache分片数一致，保证同一个key上的读改写操作原子
+type keyLocks struct {
+	mask  uint64
+	locks []sync.Mutex
+}
+
+// newKeyLocks 创建分段锁，shards必须是2的幂
+func newKeyLocks(shards int) *keyLocks {
+	if shards <= 0 {
+		shards = 1
+	}
+	return &keyLocks{mask: uint64(shards - 1), locks: make([]sync.Mutex, shards)}
+}
+
+// lock 锁定key所在的分段，返回解锁函数
+func (l *keyLocks) lock(key string) func() {
+	mu := &l.locks[hashKey(key)&l.mask]
+	mu.Lock()
+	return mu.Unlock
+}
@@ -0,0 +1,68 @@
+package lc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+)
+
+// KeyStats 单个key的统计
+type KeyStats struct {
+	// Key key
+	Key string `json:"key"`
+	// Requests 命中次数，需要开启StatsEnabled，否则为0
+	Requests uint32 `json:"requests"`
+	// Size 数据字节数，不含key
+	Size int `json:"size"`
+	// Age 距写入的时间，滑动过期模式下为距最近一次访问的时间
+	Age time.Duration `json:"age"`
+	// TTL 剩余生命周期，已过期为0
+	TTL time.Duration `json:"ttl"`
+}
+
+// keyStats 根据头部信息生成统计
+func (c *Cache) keyStats(key string, h entryHeader, data []byte, meta bigcache.Metadata) KeyStats {
+	stats := KeyStats{Key: key, Requests: meta.RequestCount, Size: len(data), Age: c.age(h)}
+	if ttl := c.lifeWindow - stats.Age; ttl > 0 {
+		stats.TTL = ttl
+	}
+	return stats
+}
+
+// KeyStats 返回key的命中次数、大小和存活时间，查询本身不计入命中统计，不影响淘汰顺序，key不存在返回ErrRecordNotFound
+func (c *Cache) KeyStats(key string) (KeyStats, error) {
+	meta := c.st.KeyMetadata(key)
+	h, data, err := c.peek(key)
+	if err == bigcache.ErrEntryNotFound {
+		return KeyStats{}, ErrRecordNotFound
+	}
+	if err != nil {
+		return KeyStats{}, err
+	}
+	return c.keyStats(key, h, data, meta), nil
+}
+
+// KeyStatsReport 遍历cache返回命中次数最多的n个key的统计，按命中次数从大到小排序，n<0返回所有key
+// 遍历不计入命中次数，数据量大时耗时较长，不建议在请求链路中调用
+func (c *Cache) KeyStatsReport(n int) ([]KeyStats, error) {
+	var report []KeyStats
+	it := c.Iterator()
+	for it.SetNext() {
+		info, err := it.Value()
+		if err == bigcache.ErrEntryNotFound {
+			// 分块缺失的数据视为不存在
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		h := entryHeader{timestamp: info.timestamp}
+		report = append(report, c.keyStats(info.Key(), h, info.Value(), c.st.KeyMetadata(info.Key())))
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Requests > report[j].Requests })
+	if n >= 0 && n < len(report) {
+		report = report[:n]
+	}
+	return report, nil
+}
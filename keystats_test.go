@@ -0,0 +1,45 @@
+package lc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestKeyStats 单测单个key的统计
+func TestKeyStats(t *testing.T) {
+	convey.Convey("TestKeyStats", t, func() {
+		for _, policy := range []EvictionPolicy{EvictionFIFO, EvictionLRU, EvictionTinyLFU} {
+			cache := createCache("test-keystats", WithStatsEnabled(true), WithLifeWindow(time.Minute),
+				WithEvictionPolicy(policy))
+			convey.So(cache.SetString("a", "aaa"), convey.ShouldBeNil)
+			convey.So(cache.SetString("b", "b"), convey.ShouldBeNil)
+			for i := 0; i < 3; i++ {
+				_, _ = cache.GetString("a")
+			}
+			_, _ = cache.GetString("b")
+
+			hits := cache.Stats().Hits
+			stats, err := cache.KeyStats("a")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(stats.Requests, convey.ShouldEqual, 3)
+			convey.So(stats.Size, convey.ShouldEqual, 3)
+			convey.So(stats.TTL, convey.ShouldBeBetween, 59*time.Second, time.Minute)
+			_, err = cache.KeyStats("not-exist")
+			convey.So(err, convey.ShouldEqual, ErrRecordNotFound)
+			// 查询不计入命中统计
+			convey.So(cache.Stats().Hits, convey.ShouldEqual, hits)
+
+			report, err := cache.KeyStatsReport(-1)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(len(report), convey.ShouldEqual, 2)
+			convey.So(report[0].Key, convey.ShouldEqual, "a")
+			convey.So(report[0].Requests, convey.ShouldEqual, 3)
+			convey.So(report[1].Key, convey.ShouldEqual, "b")
+			report, _ = cache.KeyStatsReport(1)
+			convey.So(len(report), convey.ShouldEqual, 1)
+			cache.Close()
+		}
+	})
+}
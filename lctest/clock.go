@@ -0,0 +1,105 @@
+// Package lctest 提供lc单测使用的工具
+package lctest
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// FakeClock 可控制的时钟，实现lc.Clock，时间只在调用Advance/Set时前进
+// 配合lc.WithClock使用，可立即、确定地验证数据过期、定期清理、过期兜底等逻辑
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+// waiter 等待到达deadline的After调用
+type waiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFakeClock 创建时间为now的时钟，now为零值时使用固定的2020-01-01 00:00:00 UTC
+func NewFakeClock(now time.Time) *FakeClock {
+	if now.IsZero() {
+		now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now 当前时间
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After 时钟前进d后返回当前时间，d<=0时立即返回
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, &waiter{deadline: c.now.Add(d), ch: ch})
+	c.cond.Broadcast()
+	return ch
+}
+
+// Advance 时钟前进d，触发到期的After
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(c.now.Add(d))
+}
+
+// Set 设置当前时间，早于当前时间时不触发After
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(now)
+}
+
+// setLocked 设置当前时间并按deadline顺序触发到期的After，调用方需持有锁
+func (c *FakeClock) setLocked(now time.Time) {
+	c.now = now
+	sort.Slice(c.waiters, func(i, j int) bool { return c.waiters[i].deadline.Before(c.waiters[j].deadline) })
+	n := 0
+	for ; n < len(c.waiters) && !c.waiters[n].deadline.After(now); n++ {
+		c.waiters[n].ch <- now
+	}
+	c.waiters = append(c.waiters[:0], c.waiters[n:]...)
+	c.cond.Broadcast()
+}
+
+// Waiters 正在等待的After个数
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil 阻塞直到有n个After在等待
+// Advance触发定期清理后调用BlockUntil(1)，等清理协程执行完并开始下一次等待，保证清理已完成
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// AdvanceAndWait 等待n个协程开始等待后时钟前进d，再阻塞直到被触发的协程执行完并重新开始等待
+// n为使用该时钟定期执行的协程数，如每个设置了CleanWindow的cache有一个清理协程
+func (c *FakeClock) AdvanceAndWait(d time.Duration, n int) {
+	c.BlockUntil(n)
+	c.Advance(d)
+	c.BlockUntil(n)
+}
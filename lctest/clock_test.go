@@ -0,0 +1,50 @@
+package lctest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestFakeClock 单测可控制的时钟
+func TestFakeClock(t *testing.T) {
+	convey.Convey("TestFakeClock", t, func() {
+		start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := NewFakeClock(start)
+		convey.So(clock.Now(), convey.ShouldEqual, start)
+		convey.So(NewFakeClock(time.Time{}).Now().IsZero(), convey.ShouldBeFalse)
+
+		ch := clock.After(time.Second)
+		convey.So(clock.Waiters(), convey.ShouldEqual, 1)
+		clock.Advance(500 * time.Millisecond)
+		select {
+		case <-ch:
+			t.Fatal("fired early")
+		default:
+		}
+		clock.Advance(500 * time.Millisecond)
+		convey.So(<-ch, convey.ShouldEqual, start.Add(time.Second))
+		convey.So(clock.Waiters(), convey.ShouldEqual, 0)
+		convey.So(<-clock.After(0), convey.ShouldEqual, start.Add(time.Second))
+
+		// 定期执行的协程执行完后重新等待
+		done := make(chan struct{})
+		defer close(done)
+		runs := 0
+		go func() {
+			for {
+				select {
+				case <-clock.After(time.Minute):
+					runs++
+				case <-done:
+					return
+				}
+			}
+		}()
+		clock.BlockUntil(1)
+		clock.AdvanceAndWait(time.Minute, 1)
+		clock.AdvanceAndWait(time.Minute, 1)
+		convey.So(runs, convey.ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,81 @@
+package lc
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// LimiterStats 穿透并发限制统计
+type LimiterStats struct {
+	// InFlight 正在执行的穿透函数数
+	InFlight int64 `json:"in_flight"`
+	// Waiting 排队等待的穿透请求数
+	Waiting int64 `json:"waiting"`
+	// Overloads 排队超时被拒绝的请求数
+	Overloads int64 `json:"overloads"`
+}
+
+// loadLimiter 限制不同key的并发穿透数，singleflight只能合并相同key的请求
+type loadLimiter struct {
+	sem          chan struct{}
+	queueTimeout time.Duration
+	waiting      int64
+	overloads    int64
+}
+
+// newLoadLimiter 创建并发限制，limit<=0表示不限制
+func newLoadLimiter(limit int, queueTimeout time.Duration) *loadLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &loadLimiter{sem: make(chan struct{}, limit), queueTimeout: queueTimeout}
+}
+
+// acquire 获取穿透许可，queueTimeout内获取不到返回ErrLoadOverloaded
+func (l *loadLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	default:
+	}
+	if l.queueTimeout <= 0 {
+		atomic.AddInt64(&l.overloads, 1)
+		return ErrLoadOverloaded
+	}
+	atomic.AddInt64(&l.waiting, 1)
+	defer atomic.AddInt64(&l.waiting, -1)
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	atomic.AddInt64(&l.overloads, 1)
+	return ErrLoadOverloaded
+}
+
+// release 释放穿透许可
+func (l *loadLimiter) release() {
+	if l == nil {
+		return
+	}
+	<-l.sem
+}
+
+// snapshot 返回并发限制统计
+func (l *loadLimiter) snapshot() LimiterStats {
+	if l == nil {
+		return LimiterStats{}
+	}
+	return LimiterStats{
+		InFlight:  int64(len(l.sem)),
+		Waiting:   atomic.LoadInt64(&l.waiting),
+		Overloads: atomic.LoadInt64(&l.overloads),
+	}
+}
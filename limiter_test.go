@@ -0,0 +1,69 @@
+package lc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+
+	"trpc.group/trpc-go/trpc-go/codec"
+)
+
+// TestLoadLimiter 单测并发穿透限制
+func TestLoadLimiter(t *testing.T) {
+	convey.Convey("TestLoadLimiter", t, func() {
+		cache := createCache("test-limiter", WithLoadConcurrency(1, 10*time.Millisecond))
+		defer cache.Close()
+
+		started, release := make(chan struct{}), make(chan struct{})
+		done := make(chan error)
+		go func() {
+			done <- cache.GetWithLoad(context.TODO(), "slow", &TestParam{}, func() (interface{}, error) {
+				close(started)
+				<-release
+				return &TestParam{Name: "slow"}, nil
+			}, codec.SerializationTypeJSON)
+		}()
+		<-started
+
+		// 不同key的穿透排队超时
+		err := cache.GetWithLoad(context.TODO(), "other", &TestParam{}, func() (interface{}, error) {
+			return &TestParam{Name: "other"}, nil
+		}, codec.SerializationTypeJSON)
+		convey.So(err, convey.ShouldEqual, ErrLoadOverloaded)
+		stats := cache.Stats().Limiter
+		convey.So(stats.InFlight, convey.ShouldEqual, 1)
+		convey.So(stats.Overloads, convey.ShouldEqual, 1)
+
+		close(release)
+		convey.So(<-done, convey.ShouldBeNil)
+		err = cache.GetWithLoad(context.TODO(), "other", &TestParam{}, func() (interface{}, error) {
+			return &TestParam{Name: "other"}, nil
+		}, codec.SerializationTypeJSON)
+		convey.So(err, convey.ShouldBeNil)
+
+		// 穿透超时后许可在穿透函数返回时才释放，熔断打开时不排队等待许可
+		timeoutCache := createCache("test-limiter-timeout", WithLoadConcurrency(1, time.Second),
+			WithLoadTimeout(20*time.Millisecond),
+			WithCircuitBreaker(BreakerConfig{Window: time.Hour, MinRequests: 1, ErrorRate: 1, OpenTimeout: time.Hour}))
+		defer timeoutCache.Close()
+		release = make(chan struct{})
+		err = timeoutCache.GetWithLoad(context.TODO(), "slow", &TestParam{}, func() (interface{}, error) {
+			<-release
+			return &TestParam{Name: "slow"}, nil
+		}, codec.SerializationTypeJSON)
+		convey.So(err, convey.ShouldEqual, ErrLoadTimeout)
+		convey.So(timeoutCache.Stats().Limiter.InFlight, convey.ShouldEqual, 1)
+		begin := time.Now()
+		err = timeoutCache.GetWithLoad(context.TODO(), "other", &TestParam{}, func() (interface{}, error) {
+			return &TestParam{Name: "other"}, nil
+		}, codec.SerializationTypeJSON)
+		convey.So(err, convey.ShouldEqual, ErrCircuitOpen)
+		convey.So(time.Since(begin), convey.ShouldBeLessThan, 500*time.Millisecond)
+		close(release)
+		for timeoutCache.Stats().Limiter.InFlight != 0 {
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
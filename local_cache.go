@@ -2,6 +2,7 @@ package lc
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"sync"
 	"time"
@@ -9,7 +10,6 @@ import (
 	"github.com/allegro/bigcache/v3"
 	"golang.org/x/sync/singleflight"
 
-	"trpc.group/trpc-go/trpc-go/codec"
 	"trpc.group/trpc-go/trpc-go/errs"
 	"trpc.group/trpc-go/trpc-go/log"
 )
@@ -21,14 +21,39 @@ var (
 	ErrNotCanSet = errs.New(2002, "lc: value cannot set")
 	// ErrRecordNotFound 没有发现对应记录数据
 	ErrRecordNotFound = errs.New(2003, "lc: record not found")
+	// ErrCircuitOpen 穿透函数熔断中
+	ErrCircuitOpen = errs.New(2005, "lc: circuit breaker open")
+	// ErrLoadOverloaded 并发穿透数超限
+	ErrLoadOverloaded = errs.New(2006, "lc: load overloaded")
+	// ErrLoadTimeout 穿透函数执行超时
+	ErrLoadTimeout = errs.New(2013, "lc: load timeout")
 )
 
 // Cache 缓存对象
 type Cache struct {
 	name                 string
-	bc                   *bigcache.BigCache
+	st                   store
 	group                *singleflight.Group
-	allowUseExpiredEntry bool // 是否降级
+	allowUseExpiredEntry bool          // 是否降级
+	tracer               Tracer        // 链路追踪
+	breaker              *breaker      // 穿透函数熔断器, nil表示不熔断
+	retry                *RetryPolicy  // 穿透函数重试策略, nil表示不重试
+	loadTimeout          time.Duration // 穿透函数超时时间, 0表示不超时
+	limiter              *loadLimiter  // 并发穿透限制, nil表示不限制
+	sharedLoadResult     bool          // 合并的请求是否共享穿透结果
+	ser                  Serializer    // 序列化方式, nil表示未设置
+	locks                *keyLocks     // 按key分段的写锁
+	lifeWindow           time.Duration
+	slidingExpiration    bool            // 滑动过期, 访问未过期的数据后重新计算生命周期
+	clock                Clock           // 判断过期和驱动定期清理的时钟
+	admission            *admission      // 写入准入过滤器, nil表示不过滤
+	hotKeys              *hotKeys        // 热点key统计, nil表示不统计
+	chunkSize            int             // 超过该大小的数据分块保存, 0表示不分块
+	objects              *objectStore    // 对象模式的存储, 与st为同一个对象, nil表示非对象模式
+	accessTrace          *AccessRecorder // 访问记录, nil表示不记录
+	quota                memoryQuota     // 参与内存预算的配置
+	done                 chan struct{}
+	closeOnce            sync.Once
 }
 
 var (
@@ -39,11 +64,26 @@ var (
 )
 
 // RegisterCache 注册cache对象 提前注册好处如果设置参数不合适会发生panic
+// 设置了内存预算时，静态预留的内存超过预算也会panic；已有同名cache时先关闭旧cache再替换
 func RegisterCache(name string, opts ...Option) {
+	// 先关闭旧cache退出内存预算，释放预留的内存并停止后台goroutine
+	cachePool.Lock()
+	old := cachePool.m[name]
+	delete(cachePool.m, name)
+	cachePool.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
+	// createCache加入内存预算时会加governor锁，SetMemoryBudget持有governor锁时会加cachePool锁，不能在cachePool锁内创建
 	newCache := createCache(name, opts...)
 	cachePool.Lock()
+	old = cachePool.m[name]
 	cachePool.m[name] = newCache
 	cachePool.Unlock()
+	if old != nil {
+		// 替换期间GetCache创建的cache
+		_ = old.Close()
+	}
 }
 
 // GetCache 获取cache对象 如果没有则创建一个默认cache对象
@@ -55,8 +95,19 @@ func GetCache(name string) *Cache {
 	}
 	cachePool.RUnlock()
 	// 如果没有创建一个默认cache对象
-	newCache := createCache(name)
+	var opts []Option
+	if currentGovernor() != nil {
+		// 设置了内存预算时使用可调整容量的LRU存储
+		opts = append(opts, WithEvictionPolicy(EvictionLRU))
+	}
+	newCache := createCache(name, opts...)
 	cachePool.Lock()
+	if cache, ok := cachePool.m[name]; ok {
+		// 并发创建了同名cache，使用先保存的cache，关闭多创建的cache
+		cachePool.Unlock()
+		_ = newCache.Close()
+		return cache
+	}
 	cachePool.m[name] = newCache
 	cachePool.Unlock()
 	return newCache
@@ -74,14 +125,72 @@ func createCache(name string, opts ...Option) *Cache {
 		Verbose:            false,
 		HardMaxCacheSize:   2046, // 默认最大硬件内存2046M
 		Logger:             bigcache.DefaultLogger(),
+		Tracer:             noopTracer{},
+		Clock:              systemClock{},
 	}
 	for _, opt := range opts {
 		opt(cfg)
 	}
-	bc, err := bigcache.NewBigCache(buildBigcacheConfig(cfg))
+	if cfg.Clock == nil {
+		cfg.Clock = systemClock{}
+	}
+	var memLimit int64
+	if cfg.AutoSize != nil {
+		memLimit = autoSize(name, cfg)
+	}
+	cache := &Cache{
+		name:                 name,
+		group:                &singleflight.Group{},
+		allowUseExpiredEntry: cfg.AllowUseExpiredEntry,
+		tracer:               cfg.Tracer,
+		limiter:              newLoadLimiter(cfg.MaxConcurrentLoads, cfg.LoadQueueTimeout),
+		loadTimeout:          cfg.LoadTimeout,
+		sharedLoadResult:     cfg.SharedLoadResult,
+		ser:                  cfg.Serializer,
+		locks:                newKeyLocks(cfg.Shards),
+		lifeWindow:           cfg.LifeWindow,
+		slidingExpiration:    cfg.SlidingExpiration,
+		clock:                cfg.Clock,
+		chunkSize:            cfg.ChunkSize,
+		quota:                newMemoryQuota(cfg),
+		done:                 make(chan struct{}),
+	}
+	st, err := cache.newStore(cfg)
 	if err != nil {
 		panic(err)
 	}
+	cache.st = st
+	cache.objects, _ = st.(*objectStore)
+	if err := currentGovernor().join(cache); err != nil {
+		_ = st.Close()
+		panic(err)
+	}
+	if cfg.Breaker != nil {
+		cache.breaker = newBreaker(*cfg.Breaker, cfg.Clock)
+	}
+	if cfg.Admission != nil {
+		cache.admission = newAdmission(*cfg.Admission, cfg.MaxEntriesInWindow, cfg.Shards, cfg.Clock)
+	}
+	if cfg.AutoSize != nil && cfg.AutoSize.ShrinkOnPressure && memLimit > 0 {
+		go cache.watchHeapPressure(*cfg.AutoSize, memLimit)
+	}
+	if cfg.HotKeys != nil {
+		cache.hotKeys = newHotKeys(cfg.HotKeys.Capacity, cfg.Shards)
+		cache.hotKeys.rawKeys = cfg.HotKeys.ReportRawKeys
+		if cfg.HotKeys.ReportInterval > 0 {
+			go cache.reportHotKeys(cfg.HotKeys.ReportInterval)
+		}
+	}
+	if cfg.AccessTrace != nil {
+		if cache.accessTrace, err = newAccessRecorder(*cfg.AccessTrace, cfg.Clock); err != nil {
+			_ = cache.Close()
+			panic(err)
+		}
+	}
+	if cfg.Retry != nil {
+		retry := cfg.Retry.withDefaults()
+		cache.retry = &retry
+	}
 	go func() {
 		ticker := time.NewTicker(3 * time.Minute) // 3分钟上报一次
 		defer ticker.Stop()
@@ -89,17 +198,39 @@ func createCache(name string, opts ...Option) *Cache {
 			select {
 			case <-ticker.C:
 				// length：数据条数, capacity:占用容量, stats:统计
-				log.Warnf("lc stats name:%v, length:%v, capacity:%vMB, stats:%+v", name, bc.Len(),
-					bc.Capacity()/(1024*1024), bc.Stats())
+				log.Warnf("lc stats name:%v, length:%v, capacity:%vMB, stats:%+v", name, st.Len(),
+					st.Capacity()/(1024*1024), cache.Stats())
 			}
 		}
 	}()
-	log.Warnf("new local cache, name:%v, length:%v, capacity:%vMB", name, bc.Len(), bc.Capacity()/(1024*1024))
-	return &Cache{
-		name:                 name,
-		bc:                   bc,
-		group:                &singleflight.Group{},
-		allowUseExpiredEntry: cfg.AllowUseExpiredEntry,
+	log.Warnf("new local cache, name:%v, length:%v, capacity:%vMB", name, st.Len(), st.Capacity()/(1024*1024))
+	return cache
+}
+
+// newStore 根据淘汰策略创建存储
+func (c *Cache) newStore(cfg *Config) (store, error) {
+	if cfg.ObjectMode {
+		return newObjectStore(cfg, c.isExpiredAt), nil
+	}
+	switch cfg.EvictionPolicy {
+	case "", EvictionFIFO:
+		bcCfg := buildBigcacheConfig(cfg)
+		if !isSystemClock(cfg.Clock) {
+			// bigcache内部使用真实时间淘汰和清理，自定义时钟时关闭，改为按时钟清理过期数据
+			bcCfg.LifeWindow, bcCfg.CleanWindow = bigcacheNoExpiry, 0
+		}
+		st, err := newBigcacheStore(bcCfg)
+		if err != nil {
+			return nil, err
+		}
+		if !isSystemClock(cfg.Clock) && cfg.CleanWindow > 0 {
+			go runEvery(cfg.Clock, cfg.CleanWindow, c.done, c.removeExpired)
+		}
+		return st, nil
+	case EvictionLRU, EvictionTinyLFU:
+		return newMemStore(cfg, c.isExpired), nil
+	default:
+		return nil, fmt.Errorf("lc: unknown eviction policy %q", cfg.EvictionPolicy)
 	}
 }
 
@@ -119,160 +250,444 @@ func buildBigcacheConfig(cfg *Config) bigcache.Config {
 
 // Close 会发出关闭信号，退出clean协程，保证可以被gc
 func (c *Cache) Close() error {
-	return c.bc.Close()
+	c.closeOnce.Do(func() {
+		close(c.done)
+		currentGovernor().leave(c)
+		if err := c.accessTrace.Close(); err != nil {
+			log.Errorf("lc: close access trace err: %v", err)
+		}
+	})
+	return c.st.Close()
+}
+
+// closed cache是否已关闭
+func (c *Cache) closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// recordRead 记录一次读取，用于写入准入和热点key统计
+func (c *Cache) recordRead(key string) {
+	c.admission.record(key)
+	c.hotKeys.record(key)
 }
 
 // GetBytes 获取key对应的原始二进制值，不存在返回ErrEntryNotFound
 func (c *Cache) GetBytes(key string) ([]byte, error) {
-	return c.bc.Get(key)
+	c.recordRead(key)
+	h, data, err := c.read(key)
+	c.traceRead(key, len(data), err)
+	if err != nil {
+		return nil, err
+	}
+	c.touched(key, h)
+	return data, nil
+}
+
+// View 在fn中访问key对应的原始二进制值，不存在返回ErrRecordNotFound
+// lru/tinylfu存储不拷贝数据并在fn执行期间持有分片锁，fn应尽快返回，不能持有data，也不能在fn中访问cache
+func (c *Cache) View(key string, fn func(data []byte) error) error {
+	c.recordRead(key)
+	var (
+		h        entryHeader
+		manifest []byte
+		size     int
+	)
+	visit := func(entry []byte) error {
+		var (
+			data []byte
+			err  error
+		)
+		if h, data, err = unwrapEntry(entry); err != nil {
+			return err
+		}
+		if h.flags&flagChunked != 0 {
+			// 分块在锁外读取拼接
+			manifest = append(manifest, data...)
+			return nil
+		}
+		size = len(data)
+		return fn(data)
+	}
+	var err error
+	if ms, ok := c.st.(*memStore); ok {
+		err = ms.View(key, visit)
+	} else {
+		// bigcache读取时已经拷贝了数据
+		var entry []byte
+		if entry, err = c.st.Get(key); err == nil {
+			err = visit(entry)
+		}
+	}
+	if err == nil && manifest != nil {
+		var data []byte
+		if data, err = c.readChunks(key, h, manifest, c.getChunk); err == nil {
+			size = len(data)
+			err = fn(data)
+		}
+	}
+	c.traceRead(key, size, err)
+	if err == bigcache.ErrEntryNotFound {
+		return ErrRecordNotFound
+	}
+	if err != nil {
+		return err
+	}
+	c.touched(key, h)
+	return nil
+}
+
+// GetInto 将key对应的原始二进制值追加到buf并返回，不存在返回ErrRecordNotFound
+// lru/tinylfu存储buf容量足够时不分配内存；fifo(bigcache)存储读取时bigcache总会拷贝一次数据，
+// buf容量为0时直接返回bigcache拷贝出的数据，否则追加到buf，buf容量足够时只有bigcache的一次拷贝
+func (c *Cache) GetInto(key string, buf []byte) ([]byte, error) {
+	if _, ok := c.st.(*memStore); !ok && cap(buf) == 0 {
+		data, err := c.GetBytes(key)
+		if err == bigcache.ErrEntryNotFound {
+			return buf, ErrRecordNotFound
+		}
+		if err != nil {
+			return buf, err
+		}
+		return data, nil
+	}
+	err := c.View(key, func(data []byte) error {
+		buf = append(buf, data...)
+		return nil
+	})
+	return buf, err
+}
+
+// Get 获取key对应的值，不存在返回ErrRecordNotFound
+// 指定serializationType时使用对应的codec解析，否则使用cache设置的序列化方式，未设置时使用RawSerializer
+func (c *Cache) Get(key string, val interface{}, serializationType ...int) error {
+	return c.get(key, val, c.serializer(serializationType, RawSerializer))
 }
 
-// Get 获取key对应的值，不存在返回ErrEntryNotFound, 通过输入序列化方式自动解析数据结构
-func (c *Cache) Get(key string, val interface{}, serializationType int) error {
-	entry, err := c.bc.Get(key)
-	if entry == nil || err != nil {
+// get 获取数据并使用s解析
+func (c *Cache) get(key string, val interface{}, s Serializer) error {
+	c.recordRead(key)
+	if c.objects != nil {
+		h, err := c.readObject(key, val)
+		c.traceRead(key, 0, err)
+		if err == bigcache.ErrEntryNotFound {
+			return ErrRecordNotFound
+		}
+		if err != nil {
+			return err
+		}
+		c.touched(key, h)
+		return nil
+	}
+	h, entry, err := c.read(key)
+	c.traceRead(key, len(entry), err)
+	if err != nil {
 		if err == bigcache.ErrEntryNotFound {
 			err = ErrRecordNotFound
 		}
 		return err
 	}
-	err = codec.Unmarshal(serializationType, entry, val)
+	err = s.Unmarshal(entry, val)
 	if err != nil {
 		return err
 	}
+	c.touched(key, h)
 	return nil
 }
 
 // GetWithEntryStatus 获取val以及entry status
+// 未指定serializationType并且cache未设置序列化方式时只返回entry status，不解析val
 func (c *Cache) GetWithEntryStatus(key string, val interface{}, serializationType ...int) (
 	bigcache.RemoveReason, error) {
-	entry, rsp, err := c.bc.GetWithInfo(key)
+	return c.getWithEntryStatus(context.Background(), key, val, serializationType...)
+}
+
+// getWithEntryStatus 获取val以及entry status, 反序列化过程上报span
+func (c *Cache) getWithEntryStatus(ctx context.Context, key string, val interface{}, serializationType ...int) (
+	bigcache.RemoveReason, error) {
+	c.recordRead(key)
+	if c.objects != nil {
+		h, err := c.readObject(key, val)
+		c.traceRead(key, 0, err)
+		if err != nil {
+			return bigcache.RemoveReason(0), err
+		}
+		c.touched(key, h)
+		return c.entryStatus(h), nil
+	}
+	h, entry, err := c.read(key)
+	c.traceRead(key, len(entry), err)
 	if err != nil {
 		return bigcache.RemoveReason(0), err
 	}
-	if len(serializationType) > 0 {
-		if err = codec.Unmarshal(serializationType[0], entry, val); err != nil {
+	// 与Get一致，未指定序列化方式时使用RawSerializer
+	if val != nil {
+		s := c.serializer(serializationType, RawSerializer)
+		_, span := c.startSpan(ctx, SpanUnmarshal, key)
+		span.SetAttributes(Attribute{Key: AttrSize, Value: len(entry)})
+		err = s.Unmarshal(entry, val)
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+		if err != nil {
 			return bigcache.RemoveReason(0), err
 		}
 	}
-	return rsp.EntryStatus, nil
+	c.touched(key, h)
+	return c.entryStatus(h), nil
+}
+
+// serializer 获取序列化方式，优先使用调用方指定的codec序列化类型，其次使用cache设置的序列化方式，都没有时返回dflt
+// 自动序列化模式下调用方指定的类型只影响写入，读取始终使用数据中记录的序列化方式
+func (c *Cache) serializer(serializationType []int, dflt Serializer) Serializer {
+	if _, ok := c.ser.(autoSerializer); ok {
+		if len(serializationType) > 0 {
+			return autoSerializer{fixed: serializerByType(serializationType[0])}
+		}
+		return c.ser
+	}
+	if len(serializationType) > 0 {
+		return serializerByType(serializationType[0])
+	}
+	if c.ser != nil {
+		return c.ser
+	}
+	return dflt
 }
 
 // LoadFunc 加载数据函数
 type LoadFunc func() (interface{}, error)
 
+// LoadContextFunc 带ctx的加载数据函数，设置WithLoadTimeout时ctx在超时后取消
+// 超时后GetWithLoadContext不再等待fn返回，fn应在ctx取消后尽快返回，且不能再访问调用方持有的数据
+type LoadContextFunc func(ctx context.Context) (interface{}, error)
+
 // GetWithLoad 获取key对应的value, 如果key不存在使用用户自定义fn函数加载数据返回
 // key 缓存对应的key
 // fn 缓存失效透传函数
 // serializationType 存储value的序列化方式
 func (c *Cache) GetWithLoad(ctx context.Context, key string, value interface{}, fn LoadFunc,
 	serializationType ...int) error {
-	entryStatus, err := c.GetWithEntryStatus(key, value, serializationType...)
+	return c.GetWithLoadContext(ctx, key, value, func(context.Context) (interface{}, error) {
+		return fn()
+	}, serializationType...)
+}
+
+// GetWithLoadContext 与GetWithLoad相同，穿透函数接收ctx，设置WithLoadTimeout时ctx在超时后取消
+func (c *Cache) GetWithLoadContext(ctx context.Context, key string, value interface{}, fn LoadContextFunc,
+	serializationType ...int) (err error) {
+	ctx, span := c.startSpan(ctx, SpanGetWithLoad, key)
+	status, fallback := StatusMiss, false
+	defer func() {
+		span.SetAttributes(Attribute{Key: AttrStatus, Value: status}, Attribute{Key: AttrFallback, Value: fallback})
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+	entryStatus, err := c.getWithEntryStatus(ctx, key, value, serializationType...)
 	if err == nil && entryStatus == bigcache.RemoveReason(0) {
+		status = StatusHit
 		return nil
 	}
 	expiredButNotClean := false
 	// 数据过期， 但是未清除
 	if err == nil && entryStatus == bigcache.Expired {
 		expiredButNotClean = true
+		status = StatusExpired
 	}
 	// value不能Set 直接返回错误
 	if !reflect.ValueOf(value).Elem().CanSet() {
 		return ErrNotCanSet
 	}
 	// 数据穿透, singleflight call
+	leader := false
 	rsp, err, _ := c.group.Do(key, func() (interface{}, error) {
-		newValue, fnErr := fn() // 执行穿透的func
+		leader = true
+		// 熔断打开时不调用穿透函数，直接失败，不排队等待
+		if !c.breaker.allow() {
+			return nil, ErrCircuitOpen
+		}
+		// 并发穿透数超限时排队等待，超时直接失败
+		if err := c.limiter.acquire(ctx); err != nil {
+			c.breaker.cancel()
+			return nil, err
+		}
+		_, loadSpan := c.startSpan(ctx, SpanLoad, key)
+		// 执行穿透的func, 失败时按策略重试, 超时计为失败; 穿透函数返回后才释放并发许可, 超时不释放
+		newValue, fnErr := c.load(ctx, fn, c.limiter.release)
+		c.breaker.done(fnErr)
+		if fnErr != nil {
+			loadSpan.RecordError(fnErr)
+		}
+		loadSpan.End()
 		// 从兜底cache中拉取
 		if fnErr != nil || newValue == nil {
 			// 穿透函数执行失败，留个日志
 			log.ErrorContextf(ctx, "lc through fail, err: %v, new value: %+v", fnErr, newValue)
-			return newValue, fnErr
+			if fnErr == nil {
+				fnErr = ErrRecordNotFound
+			}
+			return nil, fnErr
 		}
 		log.DebugContextf(ctx, "lc through success, key:%v, new value: %+v", key, newValue)
 		// 写cache
-		setErr := c.Set(key, newValue, serializationType...)
+		entry, setErr := c.set(ctx, key, newValue, c.serializer(serializationType, RawSerializer), true)
 		if setErr != nil {
 			log.ErrorContextf(ctx, "lc: set entry err: %v, key: %v", setErr, key)
 		}
-		return newValue, fnErr
+		return &loadResult{value: newValue, entry: entry}, nil
 	})
 	// 透传请求失败 && 数据过期未清除 && 允许使用过期entry
 	if err != nil && expiredButNotClean && c.allowUseExpiredEntry {
 		log.ErrorContextf(ctx, "lc: use expired but not clean entry, key:%v, value:%v", key, value)
+		fallback = true
 		return nil
 	}
 	if err != nil { // 数据过期并且已清除
 		return err
 	}
+	return c.copyLoadResult(rsp.(*loadResult), value, leader, serializationType...)
+}
+
+// loadResult 穿透函数的结果
+type loadResult struct {
+	value interface{} // 穿透函数返回的对象
+	entry []byte      // 序列化后的数据, 序列化失败时为nil
+}
+
+// copyLoadResult 将穿透结果拷贝到value
+// 穿透函数返回的对象只给执行穿透的请求使用, singleflight合并的其他请求从序列化数据解析或者深拷贝,
+// 保证各请求修改结果时互不影响, 开启SharedLoadResult时所有请求浅拷贝同一个对象, 对象模式与Get一致
+func (c *Cache) copyLoadResult(res *loadResult, value interface{}, leader bool, serializationType ...int) error {
+	if c.objects != nil {
+		return assignObject(value, res.value)
+	}
 	// 类型必须一致
-	if reflect.TypeOf(rsp).Kind() != reflect.TypeOf(value).Kind() {
+	if reflect.TypeOf(res.value).Kind() != reflect.TypeOf(value).Kind() ||
+		!reflect.TypeOf(res.value).Elem().AssignableTo(reflect.TypeOf(value).Elem()) {
 		return ErrTypeNotEqual
 	}
-	// copy
-	reflect.ValueOf(value).Elem().Set(reflect.ValueOf(rsp).Elem())
-	return nil
+	if leader || c.sharedLoadResult {
+		// copy
+		reflect.ValueOf(value).Elem().Set(reflect.ValueOf(res.value).Elem())
+		return nil
+	}
+	if res.entry != nil {
+		elem := reflect.ValueOf(value).Elem()
+		elem.Set(reflect.Zero(elem.Type()))
+		return c.serializer(serializationType, RawSerializer).Unmarshal(res.entry, value)
+	}
+	return deepCopyInto(value, res.value)
 }
 
-// Set 保存一对<key, value>，可能因value格式不支持而保存失败, 通过输入序列化方式自动打包数据
+// Set 保存一对<key, value>，可能因value格式不支持而保存失败
+// 指定serializationType时使用对应的codec序列化，否则使用cache设置的序列化方式，未设置时使用RawSerializer
 func (c *Cache) Set(key string, val interface{}, serializationType ...int) error {
-	entry, err := marshal(val, serializationType...)
-	if err != nil {
-		return err
-	}
-	return c.bc.Set(key, entry)
+	_, err := c.set(context.Background(), key, val, c.serializer(serializationType, RawSerializer), false)
+	return err
 }
 
-// 序列化
-func marshal(val interface{}, serializationType ...int) ([]byte, error) {
-	if len(serializationType) > 0 {
-		return codec.Marshal(serializationType[0], val)
+// set 使用s序列化并保存数据, 序列化过程上报span
+// retain为true时返回序列化后的数据，否则s实现了AppendMarshaler时直接序列化到复用的缓冲区，返回nil
+// 开启写入准入时未通过准入的数据不保存，返回nil错误；对象模式直接保存val，不序列化
+func (c *Cache) set(ctx context.Context, key string, val interface{}, s Serializer, retain bool) ([]byte, error) {
+	if c.objects != nil {
+		return nil, c.setObject(key, val)
 	}
-	var err error
-	var buf []byte
-	switch val.(type) {
-	case []byte:
-		buf = val.([]byte)
-	case string:
-		buf = []byte(val.(string))
-	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool, error:
-		if b, e := ToString(val); e != nil {
-			return buf, errs.Newf(2003, "lc: val type:%s ToString error:%s", reflect.TypeOf(val), e.Error())
-		} else {
-			buf = []byte(b)
+	buf := getEntryBuf()
+	defer putEntryBuf(buf)
+	entry := append((*buf)[:0], emptyHeader[:]...)
+	var (
+		data []byte
+		err  error
+	)
+	_, span := c.startSpan(ctx, SpanMarshal, key)
+	if am, ok := s.(AppendMarshaler); ok && !retain {
+		entry, err = am.MarshalAppend(entry, val)
+	} else if data, err = s.Marshal(val); err == nil {
+		entry = append(entry, data...)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	*buf = entry
+	if c.tracing() {
+		span.SetAttributes(Attribute{Key: AttrSize, Value: len(entry) - entryHeaderSize})
+	}
+	span.End()
+	c.traceSet(key, len(entry)-entryHeaderSize)
+	unlock := c.locks.lock(key)
+	defer unlock()
+	// 未通过准入的key不写入，同时删除旧数据，避免读到过时的值
+	if !c.admission.admit(key) {
+		if err := c.remove(key); err != nil && err != bigcache.ErrEntryNotFound {
+			return data, err
 		}
-	default:
-		err = errs.Newf(2004, "lc: value not support type:%s", reflect.TypeOf(val))
+		return data, nil
 	}
-	return buf, err
+	return data, c.writeEntry(key, entry)
 }
 
 // Delete 删除一个key
 func (c *Cache) Delete(key string) error {
-	return c.bc.Delete(key)
+	c.traceDelete(key)
+	unlock := c.locks.lock(key)
+	defer unlock()
+	return c.remove(key)
 }
 
 // Reset 清空所有cache的shard
 func (c *Cache) Reset() error {
-	return c.bc.Reset()
+	return c.st.Reset()
 }
 
-// Len 返回cache中的数据条数
+// Len 返回cache中的数据条数，开启分块时包含内部保存的分块，分块保存的数据计为清单1条加分块数条，
+// 需要准确的key数量时使用Iterator遍历
 func (c *Cache) Len() int {
-	return c.bc.Len()
+	return c.st.Len()
 }
 
 // Capacity 返回cache中的已经使用字节数
 func (c *Cache) Capacity() int {
-	return c.bc.Capacity()
+	return c.st.Capacity()
+}
+
+// Stats cache统计数据
+type Stats struct {
+	// bigcache命中统计
+	bigcache.Stats
+	// Breaker 穿透函数熔断统计
+	Breaker BreakerStats `json:"breaker"`
+	// Limiter 穿透并发限制统计
+	Limiter LimiterStats `json:"limiter"`
+	// Admission 写入准入统计
+	Admission AdmissionStats `json:"admission"`
+}
+
+// HitStats 返回存储的命中统计，与Stats().Stats相同，兼容原Stats()返回bigcache.Stats的调用方
+func (c *Cache) HitStats() bigcache.Stats {
+	return c.st.Stats()
 }
 
 // Stats 返回cache命中的统计数据
-func (c *Cache) Stats() bigcache.Stats {
-	return c.bc.Stats()
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Stats:     c.st.Stats(),
+		Breaker:   c.breaker.snapshot(),
+		Limiter:   c.limiter.snapshot(),
+		Admission: c.admission.snapshot(),
+	}
 }
 
-// Iterator 返回一个可遍历整个cache的迭代器
-func (c *Cache) Iterator() *bigcache.EntryInfoIterator {
-	return c.bc.Iterator()
+// Iterator 返回一个可遍历整个cache的迭代器，返回值由*bigcache.EntryInfoIterator改为*EntryIterator，用法不变
+func (c *Cache) Iterator() *EntryIterator {
+	return &EntryIterator{it: c.st.Iterator(), c: c}
 }
@@ -3,6 +3,7 @@ package lc
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +14,8 @@ import (
 	"google.golang.org/protobuf/types/known/anypb"
 
 	"trpc.group/trpc-go/trpc-go/codec"
+
+	"github.com/trpc-extend/lc/lctest"
 )
 
 type TestParam struct {
@@ -30,7 +33,8 @@ func TestGetOrLoad(t *testing.T) {
 			setData := &TestParam{Name: "林延秋", Age: 24,
 				ExInfo: map[string]interface{}{"Gender": true, "City": "shenzhen"},
 				Any:    anyData}
-			RegisterCache("test", WithLifeWindow(time.Second), WithCleanWindow(time.Second))
+			clock := lctest.NewFakeClock(time.Time{})
+			RegisterCache("test", WithLifeWindow(time.Second), WithCleanWindow(time.Second), WithClock(clock))
 			cache := GetCache("test")
 
 			key := "test-ok"
@@ -42,9 +46,9 @@ func TestGetOrLoad(t *testing.T) {
 			convey.So(err, convey.ShouldBeNil)
 			convey.So(getData.Name, convey.ShouldEqual, setData.Name)
 
-			<-time.After(3 * time.Second)
+			clock.AdvanceAndWait(3*time.Second, 1)
 			err = cache.Get(key, getData, codec.SerializationTypeJSON)
-			convey.So(err, convey.ShouldEqual, bigcache.ErrEntryNotFound)
+			convey.So(err, convey.ShouldEqual, ErrRecordNotFound)
 
 			loadFunc := func() (interface{}, error) {
 				return setData, nil
@@ -63,9 +67,10 @@ func TestGetOrLoad(t *testing.T) {
 			setData := &TestParam{Name: "林延秋", Age: 24,
 				ExInfo: map[string]interface{}{"Gender": true, "City": "shenzhen"},
 				Any:    anyData}
+			clock := lctest.NewFakeClock(time.Time{})
 			RegisterCache("test", WithLifeWindow(time.Second), WithCleanWindow(1*time.Second),
 				WithHardMaxCacheSize(128), WithMaxEntrySize(4096), WithMaxEntriesInWindow(2048*10),
-				WithAllowUseExpiredEntry(true))
+				WithAllowUseExpiredEntry(true), WithClock(clock))
 			cache := GetCache("test")
 
 			loadFunc := func() (interface{}, error) {
@@ -82,8 +87,8 @@ func TestGetOrLoad(t *testing.T) {
 			convey.So(err, convey.ShouldBeNil)
 			convey.So(status, convey.ShouldEqual, bigcache.RemoveReason(0))
 
-			// 数据过期&未清除
-			time.Sleep(3 * time.Second)
+			// 数据过期并已清除
+			clock.AdvanceAndWait(3*time.Second, 1)
 			status, err = cache.GetWithEntryStatus(key, getData, codec.SerializationTypeJSON)
 			convey.So(err, convey.ShouldNotBeNil)
 
@@ -97,3 +102,90 @@ func TestGetOrLoad(t *testing.T) {
 		})
 	})
 }
+
+// TestGetWithLoadIsolation 单测合并请求拿到独立的结果
+func TestGetWithLoadIsolation(t *testing.T) {
+	convey.Convey("TestGetWithLoadIsolation", t, func(c convey.C) {
+		cache := createCache("test-isolation")
+		defer cache.Close()
+
+		loadFunc := func() (interface{}, error) {
+			time.Sleep(50 * time.Millisecond)
+			return &TestParam{Name: "isolation", ExInfo: map[string]interface{}{"City": "shenzhen"}}, nil
+		}
+		results := make([]*TestParam, 5)
+		var wg sync.WaitGroup
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = &TestParam{}
+				err := cache.GetWithLoad(context.TODO(), "key", results[i], loadFunc, codec.SerializationTypeJSON)
+				c.So(err, convey.ShouldBeNil)
+			}(i)
+		}
+		wg.Wait()
+		results[0].ExInfo["City"] = "beijing"
+		for _, r := range results[1:] {
+			convey.So(r.Name, convey.ShouldEqual, "isolation")
+			convey.So(r.ExInfo["City"], convey.ShouldEqual, "shenzhen")
+		}
+	})
+
+	convey.Convey("TestDeepCopyInto", t, func() {
+		anyData, _ := anypb.New(&timestamp.Timestamp{Nanos: 1000, Seconds: 1000})
+		src := &TestParam{Name: "copy", ExInfo: map[string]interface{}{"List": []interface{}{"a"}}, Any: anyData}
+		dst := &TestParam{}
+		convey.So(deepCopyInto(dst, src), convey.ShouldBeNil)
+		convey.So(dst.Name, convey.ShouldEqual, src.Name)
+		convey.So(dst.Any, convey.ShouldNotEqual, src.Any)
+		convey.So(dst.Any.TypeUrl, convey.ShouldEqual, src.Any.TypeUrl)
+		dst.ExInfo["List"].([]interface{})[0] = "b"
+		convey.So(src.ExInfo["List"].([]interface{})[0], convey.ShouldEqual, "a")
+		convey.So(deepCopyInto(&TestParam{}, &timestamp.Timestamp{}), convey.ShouldEqual, ErrTypeNotEqual)
+	})
+}
+
+// TestRegisterCacheReplace 单测重复注册和并发GetCache
+func TestRegisterCacheReplace(t *testing.T) {
+	convey.Convey("TestRegisterCacheReplace", t, func() {
+		cachePool.Lock()
+		pool := cachePool.m
+		cachePool.m = make(map[string]*Cache)
+		cachePool.Unlock()
+		defer func() {
+			cachePool.Lock()
+			cachePool.m = pool
+			cachePool.Unlock()
+		}()
+		convey.So(SetMemoryBudget(MemoryBudget{MaxMemory: 16}), convey.ShouldBeNil)
+		defer func() { _ = SetMemoryBudget(MemoryBudget{}) }()
+
+		// 重复注册时关闭旧cache并退出内存预算，不重复预留
+		RegisterCache("test-replace", WithHardMaxCacheSize(10))
+		old := GetCache("test-replace")
+		convey.So(func() { RegisterCache("test-replace", WithHardMaxCacheSize(10)) }, convey.ShouldNotPanic)
+		convey.So(old.closed(), convey.ShouldBeTrue)
+		convey.So(GetCache("test-replace"), convey.ShouldNotEqual, old)
+		convey.So(GetCache("test-replace").closed(), convey.ShouldBeFalse)
+		convey.So(currentGovernor().reserved(), convey.ShouldEqual, 10*1024*1024)
+		_ = GetCache("test-replace").Close()
+
+		// 并发GetCache只保留一个cache
+		var wg sync.WaitGroup
+		caches := make([]*Cache, 8)
+		for i := range caches {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				caches[i] = GetCache("test-get-concurrent")
+			}(i)
+		}
+		wg.Wait()
+		for _, c := range caches {
+			convey.So(c, convey.ShouldEqual, caches[0])
+		}
+		convey.So(caches[0].closed(), convey.ShouldBeFalse)
+		_ = caches[0].Close()
+	})
+}
@@ -0,0 +1,378 @@
+package lc
+
+import (
+	"container/list"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"google.golang.org/protobuf/proto"
+
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+var (
+	// ErrObjectMode 对象模式不支持的操作，如读写原始二进制数据
+	ErrObjectMode = errs.New(2011, "lc: operation not supported in object mode")
+	// ErrNilObject 对象模式不能保存nil
+	ErrNilObject = errs.New(2012, "lc: object is nil")
+)
+
+// objectEntry 对象模式的一条数据
+type objectEntry struct {
+	key       string
+	value     interface{}
+	timestamp int64 // 写入时间, unix纳秒
+	size      int   // 估算的字节数
+	elem      *list.Element
+}
+
+// objectShard 对象存储分片，读取只加读锁，按写入顺序淘汰
+type objectShard struct {
+	mu    sync.RWMutex
+	items map[string]*objectEntry
+	ll    *list.List // 按写入时间排序, 头部为最新写入
+	size  int
+	stats bigcache.Stats // 命中统计, 原子读写
+}
+
+// objectStore 直接保存Go对象的存储，按条数和估算的字节数限制，超出时淘汰最早写入的数据
+// 实现store接口以支持Delete/Reset/Len/Stats等操作，读写二进制数据返回ErrObjectMode
+type objectStore struct {
+	shards     []*objectShard
+	mask       uint64
+	maxEntries int // 单个分片最大条数
+	maxSize    int // 单个分片最大字节数, 0表示不限制
+	isExpired  func(timestamp int64) bool
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// newObjectStore 创建对象存储
+func newObjectStore(cfg *Config, isExpired func(timestamp int64) bool) *objectStore {
+	shards := cfg.Shards
+	if shards <= 0 || shards&(shards-1) != 0 {
+		panic("lc: shards number must be power of two")
+	}
+	maxObjects := cfg.MaxObjects
+	if maxObjects <= 0 {
+		maxObjects = cfg.MaxEntriesInWindow
+	}
+	s := &objectStore{
+		shards:     make([]*objectShard, shards),
+		mask:       uint64(shards - 1),
+		maxEntries: (maxObjects + shards - 1) / shards,
+		maxSize:    cfg.HardMaxCacheSize * 1024 * 1024 / shards,
+		isExpired:  isExpired,
+		done:       make(chan struct{}),
+	}
+	for i := range s.shards {
+		s.shards[i] = &objectShard{items: make(map[string]*objectEntry), ll: list.New()}
+	}
+	if cfg.CleanWindow > 0 {
+		go s.cleanUp(cfg.Clock, cfg.CleanWindow)
+	}
+	return s
+}
+
+// shard 获取key所在的分片
+func (s *objectStore) shard(key string) *objectShard {
+	return s.shards[hashKey(key)&s.mask]
+}
+
+// getObject 获取对象及其写入时间，不存在返回bigcache.ErrEntryNotFound
+func (s *objectStore) getObject(key string) (interface{}, int64, error) {
+	sh := s.shard(key)
+	sh.mu.RLock()
+	e, ok := sh.items[key]
+	var (
+		value     interface{}
+		timestamp int64
+	)
+	if ok {
+		value, timestamp = e.value, e.timestamp
+	}
+	sh.mu.RUnlock()
+	if !ok {
+		atomic.AddInt64(&sh.stats.Misses, 1)
+		return nil, 0, bigcache.ErrEntryNotFound
+	}
+	atomic.AddInt64(&sh.stats.Hits, 1)
+	return value, timestamp, nil
+}
+
+// setObject 保存对象，超出条数或大小限制时淘汰最早写入的数据
+func (s *objectStore) setObject(key string, value interface{}, timestamp int64) error {
+	size := len(key) + objectSize(value)
+	if s.maxSize > 0 && size > s.maxSize {
+		return ErrEntryTooBig
+	}
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if e, ok := sh.items[key]; ok {
+		sh.size += size - e.size
+		e.value, e.timestamp, e.size = value, timestamp, size
+		sh.ll.MoveToFront(e.elem)
+	} else {
+		e := &objectEntry{key: key, value: value, timestamp: timestamp, size: size}
+		e.elem = sh.ll.PushFront(e)
+		sh.items[key] = e
+		sh.size += size
+	}
+	for sh.ll.Len() > s.maxEntries || (s.maxSize > 0 && sh.size > s.maxSize) {
+		sh.removeLocked(sh.ll.Back().Value.(*objectEntry))
+	}
+	return nil
+}
+
+// touchObject 将写入时间从from更新为timestamp，from为0时不检查原写入时间
+// 不存在或写入时间不是from(已被重新写入)返回bigcache.ErrEntryNotFound
+func (s *objectStore) touchObject(key string, from, timestamp int64) error {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	e, ok := sh.items[key]
+	if !ok || (from != 0 && e.timestamp != from) {
+		return bigcache.ErrEntryNotFound
+	}
+	e.timestamp = timestamp
+	sh.ll.MoveToFront(e.elem)
+	return nil
+}
+
+// removeLocked 删除数据，调用方需持有写锁
+func (sh *objectShard) removeLocked(e *objectEntry) {
+	sh.ll.Remove(e.elem)
+	delete(sh.items, e.key)
+	sh.size -= e.size
+}
+
+// Get 对象模式不支持读取二进制数据
+func (s *objectStore) Get(string) ([]byte, error) {
+	return nil, ErrObjectMode
+}
+
+// Peek 对象模式不支持读取二进制数据
+func (s *objectStore) Peek(string) ([]byte, error) {
+	return nil, ErrObjectMode
+}
+
+// Set 对象模式不支持写入二进制数据
+func (s *objectStore) Set(string, []byte) error {
+	return ErrObjectMode
+}
+
+// Delete 删除数据
+func (s *objectStore) Delete(key string) error {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	e, ok := sh.items[key]
+	if !ok {
+		atomic.AddInt64(&sh.stats.DelMisses, 1)
+		return bigcache.ErrEntryNotFound
+	}
+	atomic.AddInt64(&sh.stats.DelHits, 1)
+	sh.removeLocked(e)
+	return nil
+}
+
+// Reset 清空数据
+func (s *objectStore) Reset() error {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		sh.items = make(map[string]*objectEntry)
+		sh.ll.Init()
+		sh.size = 0
+		sh.mu.Unlock()
+	}
+	return nil
+}
+
+// Len 数据条数
+func (s *objectStore) Len() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		n += len(sh.items)
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// Capacity 估算的占用字节数
+func (s *objectStore) Capacity() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		n += sh.size
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// Stats 命中统计
+func (s *objectStore) Stats() bigcache.Stats {
+	var stats bigcache.Stats
+	for _, sh := range s.shards {
+		stats.Hits += atomic.LoadInt64(&sh.stats.Hits)
+		stats.Misses += atomic.LoadInt64(&sh.stats.Misses)
+		stats.DelHits += atomic.LoadInt64(&sh.stats.DelHits)
+		stats.DelMisses += atomic.LoadInt64(&sh.stats.DelMisses)
+	}
+	return stats
+}
+
+// KeyMetadata 对象模式不统计单个key的命中次数
+func (s *objectStore) KeyMetadata(string) bigcache.Metadata {
+	return bigcache.Metadata{}
+}
+
+// Iterator 对象模式没有二进制数据可遍历，返回空迭代器
+func (s *objectStore) Iterator() storeIterator {
+	return emptyIterator{}
+}
+
+// Close 退出清理协程
+func (s *objectStore) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return nil
+}
+
+// shrink 淘汰fraction比例最早写入的数据
+func (s *objectStore) shrink(fraction float64) {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for n := int(float64(sh.ll.Len()) * fraction); n > 0; n-- {
+			sh.removeLocked(sh.ll.Back().Value.(*objectEntry))
+		}
+		sh.mu.Unlock()
+	}
+}
+
+// cleanUp 按时钟定期删除过期数据
+func (s *objectStore) cleanUp(clock Clock, window time.Duration) {
+	runEvery(clock, window, s.done, s.removeExpired)
+}
+
+// removeExpired 删除所有过期数据
+func (s *objectStore) removeExpired() {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for _, e := range sh.items {
+			if s.isExpired(e.timestamp) {
+				sh.removeLocked(e)
+			}
+		}
+		sh.mu.Unlock()
+	}
+}
+
+// isExpiredAt 判断写入时间为timestamp的数据是否过期
+func (c *Cache) isExpiredAt(timestamp int64) bool {
+	return c.entryStatus(entryHeader{timestamp: timestamp}) == bigcache.Expired
+}
+
+// readObject 对象模式读取对象并赋值给val，val为nil时只返回头部信息，不存在返回bigcache.ErrEntryNotFound
+func (c *Cache) readObject(key string, val interface{}) (entryHeader, error) {
+	v, timestamp, err := c.objects.getObject(key)
+	if err != nil {
+		return entryHeader{}, err
+	}
+	h := entryHeader{timestamp: timestamp}
+	if val == nil {
+		return h, nil
+	}
+	return h, assignObject(val, v)
+}
+
+// setObject 对象模式保存对象，未通过准入的key不保存并删除旧数据
+func (c *Cache) setObject(key string, val interface{}) error {
+	if val == nil {
+		return ErrNilObject
+	}
+	c.traceObjectSet(key, val)
+	unlock := c.locks.lock(key)
+	defer unlock()
+	if !c.admission.admit(key) {
+		if err := c.objects.Delete(key); err != nil && err != bigcache.ErrEntryNotFound {
+			return err
+		}
+		return nil
+	}
+	return c.objects.setObject(key, val, c.now().UnixNano())
+}
+
+// emptyIterator 空迭代器
+type emptyIterator struct{}
+
+// SetNext 没有数据
+func (emptyIterator) SetNext() bool {
+	return false
+}
+
+// Value 没有数据
+func (emptyIterator) Value() (string, uint64, []byte, error) {
+	return "", 0, nil, bigcache.ErrInvalidIteratorState
+}
+
+// objectSize 估算对象占用的字节数，proto消息使用序列化后的大小，其余只计算对象本身(不含引用的数据)
+func objectSize(v interface{}) int {
+	switch o := v.(type) {
+	case proto.Message:
+		return proto.Size(o)
+	case []byte:
+		return len(o)
+	case string:
+		return len(o)
+	}
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return int(t.Size())
+}
+
+// assignObject 将对象赋值给dst，类型相同时直接赋值(指针共享同一个对象)，src为指针时浅拷贝其指向的值；
+// src和dst为同类型的proto消息时使用proto.Merge深拷贝，proto消息内部有锁和缓存，不能按值拷贝
+func assignObject(dst, src interface{}) error {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || !dv.Elem().CanSet() {
+		return ErrNotCanSet
+	}
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(dv.Elem().Type()) {
+		dv.Elem().Set(sv)
+		return nil
+	}
+	if sm, ok := src.(proto.Message); ok && sv.Type() == dv.Type() && !sv.IsNil() {
+		dm := dst.(proto.Message)
+		proto.Reset(dm)
+		proto.Merge(dm, sm)
+		return nil
+	}
+	if sv.Kind() == reflect.Ptr && !sv.IsNil() && sv.Elem().Type().AssignableTo(dv.Elem().Type()) {
+		dv.Elem().Set(sv.Elem())
+		return nil
+	}
+	return ErrTypeNotEqual
+}
+
+// keys 返回前缀为prefix的key快照
+func (s *objectStore) keys(prefix string) []string {
+	var keys []string
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		for key := range sh.items {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+		sh.mu.RUnlock()
+	}
+	return keys
+}
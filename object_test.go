@@ -0,0 +1,96 @@
+package lc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+type testObject struct {
+	Name string
+	Tags []string
+}
+
+// TestObjectMode 单测对象模式
+func TestObjectMode(t *testing.T) {
+	convey.Convey("TestObjectMode", t, func() {
+		cache := createCache("test-object", WithShards(4), WithObjectMode(8), WithLifeWindow(time.Second),
+			WithAllowUseExpiredEntry(true))
+		defer cache.Close()
+
+		// 类型相同时返回同一个指针，指向的类型浅拷贝
+		obj := &testObject{Name: "a", Tags: []string{"x"}}
+		convey.So(cache.Set("key", obj), convey.ShouldBeNil)
+		var p *testObject
+		convey.So(cache.Get("key", &p), convey.ShouldBeNil)
+		convey.So(p, convey.ShouldEqual, obj)
+		var v testObject
+		convey.So(cache.Get("key", &v), convey.ShouldBeNil)
+		convey.So(v.Name, convey.ShouldEqual, "a")
+		var s string
+		convey.So(cache.Get("key", &s), convey.ShouldEqual, ErrTypeNotEqual)
+		convey.So(cache.Get("missing", &p), convey.ShouldEqual, ErrRecordNotFound)
+		convey.So(cache.Set("nil", nil), convey.ShouldEqual, ErrNilObject)
+
+		// 不支持序列化数据的接口
+		_, err := cache.GetBytes("key")
+		convey.So(err, convey.ShouldEqual, ErrObjectMode)
+
+		// 穿透结果直接保存
+		calls := 0
+		load := func() (interface{}, error) {
+			calls++
+			return &testObject{Name: "loaded"}, nil
+		}
+		var loaded *testObject
+		convey.So(cache.GetWithLoad(context.Background(), "load", &loaded, load), convey.ShouldBeNil)
+		convey.So(loaded.Name, convey.ShouldEqual, "loaded")
+		var again *testObject
+		convey.So(cache.GetWithLoad(context.Background(), "load", &again, load), convey.ShouldBeNil)
+		convey.So(again, convey.ShouldEqual, loaded)
+		convey.So(calls, convey.ShouldEqual, 1)
+
+		// 过期后穿透失败使用过期数据兜底
+		ttl, err := cache.TTL("load")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ttl, convey.ShouldBeGreaterThan, 0)
+		time.Sleep(1100 * time.Millisecond)
+		var fallback *testObject
+		err = cache.GetWithLoad(context.Background(), "load", &fallback, func() (interface{}, error) {
+			return nil, errors.New("load fail")
+		})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(fallback, convey.ShouldEqual, loaded)
+		convey.So(cache.Touch("load"), convey.ShouldBeNil)
+		ttl, _ = cache.TTL("load")
+		convey.So(ttl, convey.ShouldBeGreaterThan, 0)
+
+		// 超过条数限制淘汰最早写入的数据
+		convey.So(cache.Reset(), convey.ShouldBeNil)
+		for i := 0; i < 100; i++ {
+			convey.So(cache.Set(strconv.Itoa(i), &testObject{Name: strconv.Itoa(i)}), convey.ShouldBeNil)
+		}
+		convey.So(cache.Len(), convey.ShouldBeLessThanOrEqualTo, 8)
+		convey.So(cache.Get("99", &p), convey.ShouldBeNil)
+		convey.So(cache.Delete("99"), convey.ShouldBeNil)
+		convey.So(cache.Get("99", &p), convey.ShouldEqual, ErrRecordNotFound)
+
+		// proto消息读取到同类型的消息时深拷贝，读取到指针时共享同一个对象
+		stored := &timestamp.Timestamp{Seconds: 1}
+		convey.So(cache.Set("pb", stored), convey.ShouldBeNil)
+		msg := &timestamp.Timestamp{Nanos: 5}
+		convey.So(cache.Get("pb", msg), convey.ShouldBeNil)
+		convey.So(msg.Seconds, convey.ShouldEqual, 1)
+		convey.So(msg.Nanos, convey.ShouldEqual, 0)
+		msg.Seconds = 2
+		convey.So(stored.Seconds, convey.ShouldEqual, 1)
+		var shared *timestamp.Timestamp
+		convey.So(cache.Get("pb", &shared), convey.ShouldBeNil)
+		convey.So(shared, convey.ShouldEqual, stored)
+	})
+}
@@ -0,0 +1,84 @@
+// Package oteltracer 基于opentelemetry实现lc.Tracer
+package oteltracer
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/trpc-extend/lc"
+)
+
+// instrumentationName 默认的instrumentation名称
+const instrumentationName = "github.com/trpc-extend/lc"
+
+// Tracer opentelemetry适配
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// New 使用指定的otel tracer创建lc.Tracer
+func New(tracer trace.Tracer) *Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+// NewFromGlobal 使用全局TracerProvider创建lc.Tracer
+func NewFromGlobal() *Tracer {
+	return New(otel.Tracer(instrumentationName))
+}
+
+// Start 创建otel span
+func (t *Tracer) Start(ctx context.Context, spanName string) (context.Context, lc.Span) {
+	ctx, span := t.tracer.Start(ctx, spanName)
+	return ctx, &Span{span: span}
+}
+
+// Span otel span适配
+type Span struct {
+	span trace.Span
+}
+
+// SetAttributes 设置span标签
+func (s *Span) SetAttributes(attrs ...lc.Attribute) {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		kvs = append(kvs, toKeyValue(a))
+	}
+	s.span.SetAttributes(kvs...)
+}
+
+// RecordError 记录错误并设置span状态
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End 结束span
+func (s *Span) End() {
+	s.span.End()
+}
+
+// toKeyValue 转换为otel标签
+func toKeyValue(a lc.Attribute) attribute.KeyValue {
+	switch v := a.Value.(type) {
+	case string:
+		return attribute.String(a.Key, v)
+	case bool:
+		return attribute.Bool(a.Key, v)
+	case int:
+		return attribute.Int(a.Key, v)
+	case int64:
+		return attribute.Int64(a.Key, v)
+	case float64:
+		return attribute.Float64(a.Key, v)
+	default:
+		return attribute.String(a.Key, fmt.Sprint(v))
+	}
+}
@@ -3,10 +3,14 @@ package plugin
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/trpc-extend/lc"
+	"google.golang.org/protobuf/proto"
 
 	"trpc.group/trpc-go/trpc-go"
 	"trpc.group/trpc-go/trpc-go/codec"
@@ -30,6 +34,14 @@ func init() {
 	plugin.Register(pluginName, &RpcCachePlugin{})
 }
 
+// tracer rpc_cache创建的cache使用的链路追踪
+var tracer lc.Tracer
+
+// SetTracer 设置rpc_cache的链路追踪，需要在插件Setup之前调用(即trpc.NewServer之前)
+func SetTracer(t lc.Tracer) {
+	tracer = t
+}
+
 // Cache 缓存对象
 type Cache struct {
 	// CacheName cache名称 用于监控上报
@@ -56,13 +68,109 @@ type Cache struct {
 	StatsEnabled bool `yaml:"stats_enabled"`
 	// Verbose 开启后输出内存申请信息
 	Verbose bool `yaml:"verbose"`
+	// CircuitBreaker 穿透函数熔断配置，不配置表示不熔断
+	CircuitBreaker *CircuitBreaker `yaml:"circuit_breaker"`
+	// Retry 穿透函数重试配置，不配置表示不重试
+	Retry *Retry `yaml:"retry"`
+	// LoadTimeout 穿透函数(包含重试)的超时时间，单位ms，0表示不超时
+	LoadTimeout int64 `yaml:"load_timeout"`
+	// MaxConcurrentLoads 不同key的最大并发穿透数，0表示不限制
+	MaxConcurrentLoads int `yaml:"max_concurrent_loads"`
+	// LoadQueueTimeout 并发穿透数超限时的排队等待时间，单位ms
+	LoadQueueTimeout int64 `yaml:"load_queue_timeout"`
+	// SharedLoadResult 合并的请求共享同一个穿透结果对象，不拷贝，要求rsp只读
+	SharedLoadResult bool `yaml:"shared_load_result"`
+	// EvictionPolicy 淘汰策略，fifo(默认)/lru/tinylfu
+	EvictionPolicy string `yaml:"eviction_policy"`
+	// Admission 写入准入配置，不配置表示所有穿透结果都保存
+	Admission *Admission `yaml:"admission"`
+	// HotKeys 热点key统计配置，不配置表示不统计
+	HotKeys *HotKeys `yaml:"hot_keys"`
+	// MemoryWeight 设置全局内存预算(lc.SetMemoryBudget)后分配内存的权重，默认1
+	MemoryWeight float64 `yaml:"memory_weight"`
+	// MinMemory 设置全局内存预算后lru/tinylfu存储保证分配的内存，单位MB
+	MinMemory int `yaml:"min_memory"`
+	// AutoSize 根据容器内存限制自动设置max_size，不配置表示使用max_size
+	AutoSize *AutoSize `yaml:"auto_size"`
+	// ChunkSize 超过该字节数的rsp分块保存，0表示不分块
+	ChunkSize int `yaml:"chunk_size"`
+	// ObjectMode 对象模式，直接保存rsp对象不序列化，只支持proto回包，要求rsp只读
+	ObjectMode bool `yaml:"object_mode"`
+	// MaxObjects 对象模式的最大数据条数，0表示使用max_entries_in_window
+	MaxObjects int `yaml:"max_objects"`
 
 	// FailoverRedis 兜底的redis配置 TODO 待支持redis兜底
 	FailoverRedis string `yaml:"failover_redis"`
 	// FailoverEviction 兜底redis key的过期时间, 默认3天过期
 	FailoverEviction uint64 `yaml:"failover_eviction"`
+	// AccessTrace 采样记录访问，用于lcsim离线评估命中率
+	AccessTrace *AccessTrace `yaml:"access_trace"`
+	// TraceOnly 只按AccessTrace记录请求和回包大小，不创建cache，用于上线cache前评估命中率
+	TraceOnly bool `yaml:"trace_only"`
 	// cache对象 根据rpc生成不同的cache
 	lc *lc.Cache `yaml:"-"`
+	// trace_only模式的访问记录
+	recorder *lc.AccessRecorder `yaml:"-"`
+}
+
+// CircuitBreaker 穿透函数熔断配置
+type CircuitBreaker struct {
+	// Window 错误率统计窗口，单位s
+	Window int64 `yaml:"window"`
+	// MinRequests 窗口内最少请求数，低于该值不触发熔断
+	MinRequests int `yaml:"min_requests"`
+	// ErrorRate 触发熔断的错误率，取值(0, 1]
+	ErrorRate float64 `yaml:"error_rate"`
+	// OpenTimeout 熔断打开后进入半开状态的时间，单位s
+	OpenTimeout int64 `yaml:"open_timeout"`
+	// HalfOpenProbes 半开状态的探测请求数
+	HalfOpenProbes int `yaml:"half_open_probes"`
+}
+
+// Retry 穿透函数重试配置，仅超时、过载、网络等框架错误码会重试
+type Retry struct {
+	// MaxAttempts 最大尝试次数(包含首次调用)
+	MaxAttempts int `yaml:"max_attempts"`
+	// InitialBackoff 首次重试前的等待时间，单位ms
+	InitialBackoff int64 `yaml:"initial_backoff"`
+	// MaxBackoff 最大等待时间，单位ms
+	MaxBackoff int64 `yaml:"max_backoff"`
+}
+
+// Admission 写入准入配置，key在窗口内被请求足够次数后才保存穿透结果
+type Admission struct {
+	// MinRequests 窗口内最少请求次数，默认2
+	MinRequests int `yaml:"min_requests"`
+	// Window 统计窗口，单位s，默认60
+	Window int64 `yaml:"window"`
+}
+
+// HotKeys 热点key统计配置
+type HotKeys struct {
+	// Capacity 跟踪的key个数，默认100
+	Capacity int `yaml:"capacity"`
+	// ReportInterval 热点key上报周期，单位s，0表示不上报
+	ReportInterval int64 `yaml:"report_interval"`
+	// ReportRawKeys 上报原始key，默认上报key的hash
+	ReportRawKeys bool `yaml:"report_raw_keys"`
+}
+
+// AutoSize 根据容器内存限制自动设置cache大小
+type AutoSize struct {
+	// Fraction cache占容器内存限制的比例，默认0.25
+	Fraction float64 `yaml:"fraction"`
+	// ShrinkOnPressure 堆内存持续超过阈值时淘汰最早的数据
+	ShrinkOnPressure bool `yaml:"shrink_on_pressure"`
+	// PressureThreshold 堆内存占容器内存限制的比例阈值，默认0.85
+	PressureThreshold float64 `yaml:"pressure_threshold"`
+}
+
+// AccessTrace 访问记录配置
+type AccessTrace struct {
+	// Path 记录文件路径，不同rpc不能使用同一个文件
+	Path string `yaml:"path"`
+	// SampleRate key的采样比例，取值(0, 1]，默认0.01
+	SampleRate float64 `yaml:"sample_rate"`
 }
 
 // RpcCachePlugin 本地Cache插件
@@ -83,17 +191,90 @@ func (t *RpcCachePlugin) Setup(name string, decoder plugin.Decoder) error {
 		return err
 	}
 	log.Infof("RpcCachePlugin Setup name:%v, caches:%+v", name, caches)
+	if err := checkCaches(caches); err != nil {
+		return err
+	}
 	t.caches = make(map[string]Cache)
 	for _, c := range caches {
-		lc.RegisterCache(c.RPCName,
+		if c.TraceOnly {
+			recorder, err := lc.NewAccessRecorder(lc.AccessTraceConfig{
+				Path:       c.AccessTrace.Path,
+				SampleRate: c.AccessTrace.SampleRate,
+			})
+			if err != nil {
+				// 关闭已经打开的访问记录文件
+				_ = t.Close()
+				return err
+			}
+			c.recorder = recorder
+			t.caches[c.RPCName] = c
+			continue
+		}
+		opts := []lc.Option{
 			lc.WithShards(c.Shards),
-			lc.WithLifeWindow(time.Duration(c.LifeWindow)*time.Second),
-			lc.WithCleanWindow(time.Duration(c.CleanWindow)*time.Second),
+			lc.WithLifeWindow(time.Duration(c.LifeWindow) * time.Second),
+			lc.WithCleanWindow(time.Duration(c.CleanWindow) * time.Second),
 			lc.WithHardMaxCacheSize(c.HardMaxCacheSize),
 			lc.WithStatsEnabled(c.StatsEnabled),
 			lc.WithVerbose(c.Verbose),
 			lc.WithMaxEntriesInWindow(c.MaxEntriesInWindow),
-			lc.WithMaxEntrySize(c.MaxEntrySize))
+			lc.WithMaxEntrySize(c.MaxEntrySize),
+			lc.WithAllowUseExpiredEntry(c.AllowUseExpiredEntry),
+			lc.WithTracer(tracer),
+			lc.WithLoadConcurrency(c.MaxConcurrentLoads, time.Duration(c.LoadQueueTimeout)*time.Millisecond),
+			lc.WithLoadTimeout(time.Duration(c.LoadTimeout) * time.Millisecond),
+			lc.WithSharedLoadResult(c.SharedLoadResult),
+			lc.WithEvictionPolicy(lc.EvictionPolicy(c.EvictionPolicy)),
+			lc.WithMemoryWeight(c.MemoryWeight),
+			lc.WithMinMemory(c.MinMemory),
+			lc.WithChunking(c.ChunkSize),
+		}
+		if a := c.Admission; a != nil {
+			opts = append(opts, lc.WithAdmission(lc.AdmissionConfig{
+				MinRequests: a.MinRequests,
+				Window:      time.Duration(a.Window) * time.Second,
+			}))
+		}
+		if c.ObjectMode {
+			opts = append(opts, lc.WithObjectMode(c.MaxObjects))
+		}
+		if a := c.AutoSize; a != nil {
+			opts = append(opts, lc.WithAutoSize(lc.AutoSizeConfig{
+				Fraction:          a.Fraction,
+				ShrinkOnPressure:  a.ShrinkOnPressure,
+				PressureThreshold: a.PressureThreshold,
+			}))
+		}
+		if h := c.HotKeys; h != nil {
+			opts = append(opts, lc.WithHotKeys(lc.HotKeysConfig{
+				Capacity:       h.Capacity,
+				ReportInterval: time.Duration(h.ReportInterval) * time.Second,
+				ReportRawKeys:  h.ReportRawKeys,
+			}))
+		}
+		if b := c.CircuitBreaker; b != nil {
+			opts = append(opts, lc.WithCircuitBreaker(lc.BreakerConfig{
+				Window:         time.Duration(b.Window) * time.Second,
+				MinRequests:    b.MinRequests,
+				ErrorRate:      b.ErrorRate,
+				OpenTimeout:    time.Duration(b.OpenTimeout) * time.Second,
+				HalfOpenProbes: b.HalfOpenProbes,
+			}))
+		}
+		if a := c.AccessTrace; a != nil {
+			opts = append(opts, lc.WithAccessTrace(lc.AccessTraceConfig{
+				Path:       a.Path,
+				SampleRate: a.SampleRate,
+			}))
+		}
+		if r := c.Retry; r != nil {
+			opts = append(opts, lc.WithRetryPolicy(lc.RetryPolicy{
+				MaxAttempts:    r.MaxAttempts,
+				InitialBackoff: time.Duration(r.InitialBackoff) * time.Millisecond,
+				MaxBackoff:     time.Duration(r.MaxBackoff) * time.Millisecond,
+			}))
+		}
+		lc.RegisterCache(c.RPCName, opts...)
 		c.lc = lc.GetCache(c.RPCName)
 		t.caches[c.RPCName] = c
 	}
@@ -101,6 +282,37 @@ func (t *RpcCachePlugin) Setup(name string, decoder plugin.Decoder) error {
 	return nil
 }
 
+// checkCaches 检查配置，trace_only必须配置access_trace，不同rpc的access_trace不能写入同一个文件
+func checkCaches(caches []Cache) error {
+	paths := make(map[string]string)
+	for _, c := range caches {
+		if c.TraceOnly && c.AccessTrace == nil {
+			return fmt.Errorf("rpc_cache %s: trace_only requires access_trace", c.RPCName)
+		}
+		if c.AccessTrace == nil {
+			continue
+		}
+		path := filepath.Clean(c.AccessTrace.Path)
+		if rpcName, ok := paths[path]; ok {
+			return fmt.Errorf("rpc_cache %s: access_trace path %s already used by %s", c.RPCName, path, rpcName)
+		}
+		paths[path] = c.RPCName
+	}
+	return nil
+}
+
+// Close 实现plugin.Closer，服务退出时写入trace_only模式缓冲的访问记录并关闭文件
+func (t *RpcCachePlugin) Close() error {
+	var err error
+	for _, c := range t.caches {
+		if cerr := c.recorder.Close(); cerr != nil {
+			log.Errorf("RpcCachePlugin close access trace failed! rpc:%v, err:%v", c.RPCName, cerr)
+			err = cerr
+		}
+	}
+	return err
+}
+
 // ServerFilter rpc_cache服务端拦截器
 func ServerFilter(t *RpcCachePlugin) filter.ServerFilter {
 	return func(ctx context.Context, req interface{}, handle filter.ServerHandleFunc) (interface{}, error) {
@@ -108,15 +320,20 @@ func ServerFilter(t *RpcCachePlugin) filter.ServerFilter {
 		if v, ok := t.caches[rpcName]; ok && v.lc != nil {
 			keyFunc := GetKeyFunc(rpcName)
 			key, newRsp := keyFunc(ctx, req)
-			if key != "" && newRsp != nil {
+			if key != "" && newRsp != nil && v.cacheable(newRsp) {
 				// 命中缓存配置策略
-				hitCacheFlag := HitCacheFlag
-				loadFunc := func() (interface{}, error) {
-					hitCacheFlag = NoHitCacheFlag
-					subRsp, subErr := handle(ctx, req)
+				var loaded int32
+				loadFunc := func(loadCtx context.Context) (interface{}, error) {
+					atomic.StoreInt32(&loaded, 1)
+					subRsp, subErr := handle(loadCtx, req)
+					if m, ok := subRsp.(proto.Message); ok && subErr == nil && v.ObjectMode {
+						// 对象模式按引用保存，handler可能复用返回的rsp，保存拷贝
+						return proto.Clone(m), nil
+					}
 					return subRsp, subErr
 				}
-				err := v.lc.GetWithLoad(ctx, key, newRsp, loadFunc, v.SerializationType)
+				err := v.lc.GetWithLoadContext(ctx, key, newRsp, loadFunc, v.SerializationType)
+				hitCacheFlag := cacheFlag(&loaded)
 				trpc.SetMetaData(ctx, fmt.Sprintf("%s_%s", pluginName, v.CacheName), []byte(hitCacheFlag))
 				reportCacheMonitor(v.CacheName, rpcName, hitCacheFlag, err)
 				return newRsp, err
@@ -126,6 +343,13 @@ func ServerFilter(t *RpcCachePlugin) filter.ServerFilter {
 				trpc.SetMetaData(ctx, fmt.Sprintf("%s_%s", pluginName, v.CacheName), []byte(ForbidCacheFlag))
 				return handle(ctx, req)
 			}
+		} else if ok && v.recorder != nil {
+			// 只记录访问
+			rsp, err := handle(ctx, req)
+			if err == nil {
+				recordRequest(ctx, v.recorder, rpcName, req, rsp)
+			}
+			return rsp, err
 		} else {
 			// 如果接口没有配置
 			return handle(ctx, req)
@@ -140,15 +364,19 @@ func ClientFilter(t *RpcCachePlugin) filter.ClientFilter {
 		if v, ok := t.caches[rpcName]; ok && v.lc != nil {
 			keyFunc := GetKeyFunc(rpcName)
 			key, _ := keyFunc(ctx, req)
-			if key != "" && rsp != nil {
+			if key != "" && rsp != nil && v.cacheable(rsp) {
 				// 命中缓存配置策略
-				hitCacheFlag := HitCacheFlag
-				loadFunc := func() (interface{}, error) {
-					hitCacheFlag = NoHitCacheFlag
-					err := handle(ctx, req, rsp)
-					return rsp, err
+				var loaded int32
+				loadFunc := func(loadCtx context.Context) (interface{}, error) {
+					atomic.StoreInt32(&loaded, 1)
+					// 穿透超时后GetWithLoadContext已返回，写入新的rsp，成功后才拷贝给调用方的rsp
+					// 对象模式保存的是新的rsp，调用方的rsp不会被cache引用
+					newRsp := reflect.New(reflect.TypeOf(rsp).Elem()).Interface()
+					err := handle(loadCtx, req, newRsp)
+					return newRsp, err
 				}
-				err := v.lc.GetWithLoad(ctx, key, rsp, loadFunc, v.SerializationType)
+				err := v.lc.GetWithLoadContext(ctx, key, rsp, loadFunc, v.SerializationType)
+				hitCacheFlag := cacheFlag(&loaded)
 				trpc.SetMetaData(ctx, fmt.Sprintf("%s_%s", pluginName, v.CacheName), []byte(hitCacheFlag))
 				reportCacheMonitor(v.CacheName, rpcName, hitCacheFlag, err)
 				return err
@@ -158,6 +386,13 @@ func ClientFilter(t *RpcCachePlugin) filter.ClientFilter {
 				trpc.SetMetaData(ctx, fmt.Sprintf("%s_%s", pluginName, v.CacheName), []byte(ForbidCacheFlag))
 				return handle(ctx, req, rsp)
 			}
+		} else if ok && v.recorder != nil {
+			// 只记录访问
+			err := handle(ctx, req, rsp)
+			if err == nil {
+				recordRequest(ctx, v.recorder, rpcName, req, rsp)
+			}
+			return err
 		} else {
 			// 如果接口没有配置
 			return handle(ctx, req, rsp)
@@ -165,6 +400,36 @@ func ClientFilter(t *RpcCachePlugin) filter.ClientFilter {
 	}
 }
 
+// cacheable 对象模式直接保存穿透结果，proto回包读写时深拷贝，其他类型只能浅拷贝，会与cache共享数据，不使用cache
+func (c Cache) cacheable(rsp interface{}) bool {
+	if !c.ObjectMode {
+		return true
+	}
+	_, ok := rsp.(proto.Message)
+	return ok
+}
+
+// cacheFlag 按是否执行了穿透返回命中标记，穿透函数超时后可能仍在执行，需要原子读取
+func cacheFlag(loaded *int32) string {
+	if atomic.LoadInt32(loaded) == 1 {
+		return NoHitCacheFlag
+	}
+	return HitCacheFlag
+}
+
+// recordRequest trace_only模式记录一次成功的请求，proto回包按序列化后的大小记录，其他回包大小记为0
+func recordRequest(ctx context.Context, recorder *lc.AccessRecorder, rpcName string, req, rsp interface{}) {
+	key, _ := GetKeyFunc(rpcName)(ctx, req)
+	if key == "" || !recorder.Sampled(key) {
+		return
+	}
+	size := 0
+	if m, ok := rsp.(proto.Message); ok {
+		size = proto.Size(m)
+	}
+	recorder.Record(lc.AccessRequest, key, size)
+}
+
 // reportCacheMonitor 上报缓存监控数据
 func reportCacheMonitor(cacheName, rpcName, hitCacheFlag string, err error) {
 	hit := int64(0)
@@ -0,0 +1,154 @@
+package lc
+
+import "container/list"
+
+// W-TinyLFU分段
+const (
+	segWindow uint8 = iota
+	segProbation
+	segProtected
+)
+
+// tinyLFUPolicy W-TinyLFU淘汰策略
+// 新数据先进入窗口LRU(1%容量)，被挤出窗口后与主区(SLRU)尾部数据比较访问频率，频率更高才能进入主区
+// 主区分为试用区和保护区(80%)，试用区数据再次被访问后进入保护区
+type tinyLFUPolicy struct {
+	freq         *tinyLFU
+	segs         [3]*list.List
+	costs        [3]int
+	windowCap    int
+	mainCap      int
+	protectedCap int
+}
+
+// newTinyLFUPolicy 创建W-TinyLFU淘汰策略，capacity为最大字节数，counters为预估的key数量
+func newTinyLFUPolicy(capacity, counters int) *tinyLFUPolicy {
+	p := &tinyLFUPolicy{
+		freq: newTinyLFU(counters),
+		segs: [3]*list.List{list.New(), list.New(), list.New()},
+	}
+	p.setCapacity(capacity)
+	return p
+}
+
+// setCapacity 按比例划分窗口、主区和保护区容量
+func (p *tinyLFUPolicy) setCapacity(capacity int) {
+	p.windowCap = capacity / 100
+	p.mainCap = capacity - p.windowCap
+	p.protectedCap = p.mainCap * 8 / 10
+}
+
+// resize 调整容量，保护区超出的数据降级到试用区，再按准入规则淘汰
+func (p *tinyLFUPolicy) resize(capacity int) []*memEntry {
+	p.setCapacity(capacity)
+	p.demote()
+	return p.balance()
+}
+
+// push 将数据放到分段头部
+func (p *tinyLFUPolicy) push(e *memEntry, seg uint8) {
+	e.seg = seg
+	e.elem = p.segs[seg].PushFront(e)
+	p.costs[seg] += e.cost
+}
+
+// remove 从所在分段删除
+func (p *tinyLFUPolicy) remove(e *memEntry) {
+	p.segs[e.seg].Remove(e.elem)
+	p.costs[e.seg] -= e.cost
+}
+
+// add 新数据进入窗口
+func (p *tinyLFUPolicy) add(e *memEntry) []*memEntry {
+	p.freq.increment(e.hash)
+	p.push(e, segWindow)
+	return p.balance()
+}
+
+// update 重新写入视为一次访问
+func (p *tinyLFUPolicy) update(e *memEntry, delta int) []*memEntry {
+	p.costs[e.seg] += delta
+	p.access(e)
+	return p.balance()
+}
+
+// access 访问数据，试用区数据晋升到保护区，保护区超出容量时尾部降级到试用区
+func (p *tinyLFUPolicy) access(e *memEntry) {
+	p.freq.increment(e.hash)
+	if e.seg != segProbation {
+		p.segs[e.seg].MoveToFront(e.elem)
+		return
+	}
+	p.remove(e)
+	p.push(e, segProtected)
+	p.demote()
+}
+
+// demote 保护区超出容量时尾部降级到试用区
+func (p *tinyLFUPolicy) demote() {
+	for p.costs[segProtected] > p.protectedCap && p.segs[segProtected].Len() > 1 {
+		demoted := p.segs[segProtected].Back().Value.(*memEntry)
+		p.remove(demoted)
+		p.push(demoted, segProbation)
+	}
+}
+
+// miss 未命中也计入访问频率
+func (p *tinyLFUPolicy) miss(hash uint64) {
+	p.freq.increment(hash)
+}
+
+// reset 清空
+func (p *tinyLFUPolicy) reset() {
+	for i := range p.segs {
+		p.segs[i].Init()
+		p.costs[i] = 0
+	}
+	p.freq.reset()
+}
+
+// mainCost 主区占用字节数
+func (p *tinyLFUPolicy) mainCost() int {
+	return p.costs[segProbation] + p.costs[segProtected]
+}
+
+// victim 主区中下一个被淘汰的数据，优先淘汰试用区
+func (p *tinyLFUPolicy) victim() *memEntry {
+	for _, seg := range []uint8{segProbation, segProtected} {
+		if back := p.segs[seg].Back(); back != nil {
+			return back.Value.(*memEntry)
+		}
+	}
+	return nil
+}
+
+// balance 将超出窗口容量的数据移入主区，返回被淘汰的数据
+func (p *tinyLFUPolicy) balance() []*memEntry {
+	var evicted []*memEntry
+	for p.costs[segWindow] > p.windowCap && p.segs[segWindow].Len() > 0 {
+		cand := p.segs[segWindow].Back().Value.(*memEntry)
+		p.remove(cand)
+		admitted := true
+		for p.mainCost()+cand.cost > p.mainCap {
+			v := p.victim()
+			if v == nil || p.freq.estimate(cand.hash) <= p.freq.estimate(v.hash) {
+				admitted = false
+				break
+			}
+			p.remove(v)
+			evicted = append(evicted, v)
+		}
+		if !admitted {
+			evicted = append(evicted, cand)
+			continue
+		}
+		p.push(cand, segProbation)
+	}
+	// 重新写入的数据变大后主区可能超出容量
+	for p.mainCost() > p.mainCap {
+		v := p.victim()
+		p.remove(v)
+		evicted = append(evicted, v)
+	}
+	return evicted
+}
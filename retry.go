@@ -0,0 +1,132 @@
+package lc
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"trpc.group/trpc-go/trpc-go/errs"
+	"trpc.group/trpc-go/trpc-go/log"
+)
+
+// RetryPolicy 穿透函数重试策略，在singleflight内执行，合并的请求共享重试结果
+type RetryPolicy struct {
+	// MaxAttempts 最大尝试次数(包含首次调用)，<=1表示不重试
+	MaxAttempts int `yaml:"max_attempts"`
+	// InitialBackoff 首次重试前的等待时间，默认10ms
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	// MaxBackoff 最大等待时间，默认1s
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// Multiplier 每次重试等待时间的增长倍数，默认2
+	Multiplier float64 `yaml:"multiplier"`
+	// Jitter 等待时间随机抖动比例，取值[0, 1]，默认0.2
+	Jitter float64 `yaml:"jitter"`
+	// Retryable 判断错误是否可以重试，默认DefaultRetryable
+	Retryable func(err error) bool `yaml:"-"`
+}
+
+// retryableCodes 默认可重试的错误码
+var retryableCodes = map[int]bool{
+	int(errs.RetServerTimeout):         true,
+	int(errs.RetServerOverload):        true,
+	int(errs.RetServerFullLinkTimeout): true,
+	int(errs.RetServerSystemErr):       true,
+	int(errs.RetClientTimeout):         true,
+	int(errs.RetClientConnectFail):     true,
+	int(errs.RetClientNetErr):          true,
+	int(errs.RetClientReadFrameErr):    true,
+}
+
+// DefaultRetryable 默认重试判断，仅超时、过载、网络等框架错误码可以重试
+func DefaultRetryable(err error) bool {
+	return retryableCodes[int(errs.Code(err))]
+}
+
+// withDefaults 填充默认值
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 10 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.Jitter <= 0 || p.Jitter > 1 {
+		p.Jitter = 0.2
+	}
+	if p.Retryable == nil {
+		p.Retryable = DefaultRetryable
+	}
+	return p
+}
+
+// backoff 第attempt次重试前的等待时间，attempt从1开始
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < attempt && d < float64(p.MaxBackoff); i++ {
+		d *= p.Multiplier
+	}
+	if d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	// 在[d*(1-jitter), d*(1+jitter)]之间随机
+	d += d * p.Jitter * (2*rand.Float64() - 1)
+	return time.Duration(d)
+}
+
+// load 按重试策略执行穿透函数，ctx结束时停止重试
+func (p *RetryPolicy) load(ctx context.Context, fn LoadContextFunc) (interface{}, error) {
+	if p == nil || p.MaxAttempts <= 1 {
+		return fn(ctx)
+	}
+	var (
+		value interface{}
+		err   error
+	)
+	for attempt := 1; ; attempt++ {
+		value, err = fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.Retryable(err) {
+			return value, err
+		}
+		wait := p.backoff(attempt)
+		log.DebugContextf(ctx, "lc: load fail, retry after %v, attempt: %d, err: %v", wait, attempt, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return value, err
+		case <-timer.C:
+		}
+	}
+}
+
+// loadResponse 穿透函数的返回值
+type loadResponse struct {
+	value interface{}
+	err   error
+}
+
+// load 按重试策略执行穿透函数，设置了loadTimeout时超时返回ErrLoadTimeout并取消穿透函数的ctx，不再等待穿透函数返回
+// 穿透函数(包含重试)返回后调用done，超时时在后台执行完成后调用
+func (c *Cache) load(ctx context.Context, fn LoadContextFunc, done func()) (interface{}, error) {
+	if c.loadTimeout <= 0 {
+		defer done()
+		return c.retry.load(ctx, fn)
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.loadTimeout)
+	defer cancel()
+	ch := make(chan loadResponse, 1)
+	go func() {
+		defer done()
+		value, err := c.retry.load(ctx, fn)
+		ch <- loadResponse{value: value, err: err}
+	}()
+	select {
+	case rsp := <-ch:
+		return rsp.value, rsp.err
+	case <-ctx.Done():
+		return nil, ErrLoadTimeout
+	}
+}
@@ -0,0 +1,67 @@
+package lc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+// TestRetryPolicy 单测穿透函数重试
+func TestRetryPolicy(t *testing.T) {
+	convey.Convey("TestRetryPolicy", t, func() {
+		cache := createCache("test-retry", WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond}))
+		defer cache.Close()
+
+		convey.Convey("retry retryable error then succ", func(c convey.C) {
+			var calls int32
+			loadFunc := func() (interface{}, error) {
+				if atomic.AddInt32(&calls, 1) < 3 {
+					time.Sleep(20 * time.Millisecond)
+					return nil, errs.New(errs.RetClientTimeout, "timeout")
+				}
+				return &TestParam{Name: "retry"}, nil
+			}
+			// 合并的请求共享重试结果
+			var wg sync.WaitGroup
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					getData := &TestParam{}
+					err := cache.GetWithLoad(context.TODO(), "key-retry", getData, loadFunc,
+						codec.SerializationTypeJSON)
+					c.So(err, convey.ShouldBeNil)
+					c.So(getData.Name, convey.ShouldEqual, "retry")
+				}()
+			}
+			wg.Wait()
+			convey.So(atomic.LoadInt32(&calls), convey.ShouldEqual, 3)
+		})
+
+		convey.Convey("not retry unknown error", func() {
+			calls := 0
+			err := cache.GetWithLoad(context.TODO(), "key-fail", &TestParam{}, func() (interface{}, error) {
+				calls++
+				return nil, errors.New("fail")
+			}, codec.SerializationTypeJSON)
+			convey.So(err, convey.ShouldNotBeNil)
+			convey.So(calls, convey.ShouldEqual, 1)
+		})
+
+		convey.Convey("backoff bounded", func() {
+			p := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}.withDefaults()
+			for attempt := 1; attempt < 10; attempt++ {
+				convey.So(p.backoff(attempt), convey.ShouldBeLessThanOrEqualTo, 60*time.Millisecond)
+			}
+		})
+	})
+}
@@ -0,0 +1,104 @@
+package lc
+
+import (
+	"strings"
+
+	"github.com/allegro/bigcache/v3"
+)
+
+// Scan 遍历前缀为prefix的数据，fn返回false时停止遍历，返回遍历的数据条数
+// 包含已过期但未清除的数据；遍历期间的并发写入可能读到也可能读不到，fn中不能持有entry；对象模式返回ErrObjectMode
+func (c *Cache) Scan(prefix string, fn func(key string, entry []byte) bool) (int, error) {
+	if c.objects != nil {
+		return 0, ErrObjectMode
+	}
+	n := 0
+	it := c.Iterator()
+	for it.SetNext() {
+		info, err := it.Value()
+		if err == bigcache.ErrEntryNotFound {
+			// 分块缺失的数据视为不存在
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		if !strings.HasPrefix(info.Key(), prefix) {
+			continue
+		}
+		n++
+		if !fn(info.Key(), info.Value()) {
+			break
+		}
+	}
+	return n, nil
+}
+
+// Keys 返回前缀为prefix的所有key，prefix为空返回所有key
+func (c *Cache) Keys(prefix string) ([]string, error) {
+	if c.objects != nil {
+		return c.objects.keys(prefix), nil
+	}
+	var keys []string
+	it := c.st.Iterator()
+	for it.SetNext() {
+		key, _, _, err := it.Value()
+		if err != nil {
+			return nil, err
+		}
+		if !isChunkKey(key) && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// DeleteWhere 删除predicate返回true的数据，返回删除的条数；对象模式返回ErrObjectMode
+// 先遍历找出候选key，删除前持有key的分段锁重新读取并判断，遍历后并发写入的新数据不满足条件时不会被误删
+func (c *Cache) DeleteWhere(predicate func(key string, entry []byte) bool) (int, error) {
+	if c.objects != nil {
+		return 0, ErrObjectMode
+	}
+	var keys []string
+	if _, err := c.Scan("", func(key string, entry []byte) bool {
+		if predicate(key, entry) {
+			keys = append(keys, key)
+		}
+		return true
+	}); err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, key := range keys {
+		if c.deleteIf(key, predicate) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// deleteIf 持有key的分段锁重新读取数据，满足predicate时删除
+func (c *Cache) deleteIf(key string, predicate func(key string, entry []byte) bool) bool {
+	unlock := c.locks.lock(key)
+	defer unlock()
+	_, data, err := c.read(key)
+	if err != nil || !predicate(key, data) {
+		return false
+	}
+	return c.remove(key) == nil
+}
+
+// DeletePrefix 删除前缀为prefix的数据，返回删除的条数，不读取数据内容
+func (c *Cache) DeletePrefix(prefix string) (int, error) {
+	keys, err := c.Keys(prefix)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, key := range keys {
+		if c.Delete(key) == nil {
+			n++
+		}
+	}
+	return n, nil
+}
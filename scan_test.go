@@ -0,0 +1,68 @@
+package lc
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestScan 单测按前缀遍历和删除
+func TestScan(t *testing.T) {
+	convey.Convey("TestScan", t, func() {
+		for _, policy := range []EvictionPolicy{EvictionFIFO, EvictionLRU} {
+			cache := createCache("test-scan-"+string(policy), WithShards(16), WithHardMaxCacheSize(64),
+				WithEvictionPolicy(policy))
+			for i := 0; i < 10; i++ {
+				convey.So(cache.Set("user:"+strconv.Itoa(i), strconv.Itoa(i)), convey.ShouldBeNil)
+				convey.So(cache.Set("item:"+strconv.Itoa(i), strconv.Itoa(i)), convey.ShouldBeNil)
+			}
+
+			keys, err := cache.Keys("user:")
+			convey.So(err, convey.ShouldBeNil)
+			sort.Strings(keys)
+			convey.So(len(keys), convey.ShouldEqual, 10)
+			convey.So(keys[0], convey.ShouldEqual, "user:0")
+
+			seen := 0
+			n, err := cache.Scan("item:", func(key string, entry []byte) bool {
+				seen++
+				return seen < 3
+			})
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(n, convey.ShouldEqual, 3)
+
+			// 删除value为奇数的item
+			n, err = cache.DeleteWhere(func(key string, entry []byte) bool {
+				i, err := strconv.Atoi(string(entry))
+				return err == nil && key[:5] == "item:" && i%2 == 1
+			})
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(n, convey.ShouldEqual, 5)
+			convey.So(cache.Len(), convey.ShouldEqual, 15)
+
+			n, err = cache.DeletePrefix("user:")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(n, convey.ShouldEqual, 10)
+			keys, _ = cache.Keys("")
+			convey.So(len(keys), convey.ShouldEqual, 5)
+			cache.Close()
+		}
+
+		// 对象模式支持按前缀获取和删除key
+		objects := createCache("test-scan-object", WithShards(4), WithObjectMode(100))
+		defer objects.Close()
+		convey.So(objects.Set("a:1", &testObject{}), convey.ShouldBeNil)
+		convey.So(objects.Set("b:1", &testObject{}), convey.ShouldBeNil)
+		keys, err := objects.Keys("a:")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(keys, convey.ShouldResemble, []string{"a:1"})
+		_, err = objects.Scan("", func(string, []byte) bool { return true })
+		convey.So(err, convey.ShouldEqual, ErrObjectMode)
+		n, err := objects.DeletePrefix("a:")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, 1)
+		convey.So(objects.Len(), convey.ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,311 @@
+package lc
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"trpc.group/trpc-go/trpc-go/codec"
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+// Serializer 序列化接口，可通过RegisterSerializer注册自定义实现
+type Serializer interface {
+	// ID 序列化方式标识，全局唯一: 0x00~0x7F为trpc codec序列化类型0~127，0x80~0xBF为lc内置(0x80~0x82)
+	// 和自定义序列化方式，0xC0~0xFF为trpc codec序列化类型128~191
+	ID() byte
+	// Marshal 序列化
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal 反序列化
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// AppendMarshaler 可选接口，序列化结果追加到dst，Set时直接写入复用的缓冲区，减少内存分配
+type AppendMarshaler interface {
+	// MarshalAppend 序列化v并追加到dst，返回追加后的slice
+	MarshalAppend(dst []byte, v interface{}) ([]byte, error)
+}
+
+// lc内置序列化方式标识
+const (
+	SerializerIDRaw  byte = 0x80 // 原始二进制、字符串以及基础类型
+	SerializerIDGob  byte = 0x81 // encoding/gob
+	SerializerIDAuto byte = 0x82 // 根据数据类型自动选择，并在数据头部记录选择的序列化方式
+)
+
+// serializerIDCodecHigh trpc codec序列化类型128~191的标识从0xC0开始，避免与lc内置和自定义的标识冲突
+const serializerIDCodecHigh byte = 0xC0
+
+// 内置序列化方式
+var (
+	// PBSerializer 对应codec.SerializationTypePB
+	PBSerializer Serializer = CodecSerializer(codec.SerializationTypePB)
+	// JSONSerializer 对应codec.SerializationTypeJSON
+	JSONSerializer Serializer = CodecSerializer(codec.SerializationTypeJSON)
+	// FlatBufferSerializer 对应codec.SerializationTypeFlatBuffer
+	FlatBufferSerializer Serializer = CodecSerializer(codec.SerializationTypeFlatBuffer)
+	// NoopSerializer 对应codec.SerializationTypeNoop
+	NoopSerializer Serializer = CodecSerializer(codec.SerializationTypeNoop)
+	// XMLSerializer 对应codec.SerializationTypeXML
+	XMLSerializer Serializer = CodecSerializer(codec.SerializationTypeXML)
+	// RawSerializer 支持[]byte、string以及ToString/ToInterface支持的基础类型，未设置序列化方式时默认使用
+	RawSerializer Serializer = rawSerializer{}
+	// GobSerializer 使用encoding/gob序列化
+	GobSerializer Serializer = gobSerializer{}
+	// AutoSerializer 根据数据类型自动选择序列化方式并记录在数据中，反序列化时使用记录的方式:
+	// proto.Message使用PB，[]byte、string以及基础类型使用Raw，其余使用JSON
+	AutoSerializer Serializer = autoSerializer{}
+)
+
+// ErrInvalidEntry 数据格式错误
+var ErrInvalidEntry = errs.New(2007, "lc: invalid entry")
+
+// ErrSerializerConflict 注册的序列化方式标识与codec序列化类型或已注册的其他序列化方式冲突
+var ErrSerializerConflict = errs.New(2014, "lc: serializer id conflict")
+
+var serializers = struct {
+	sync.RWMutex
+	m map[byte]Serializer
+}{m: make(map[byte]Serializer)}
+
+func init() {
+	for _, s := range []Serializer{PBSerializer, JSONSerializer, FlatBufferSerializer, NoopSerializer,
+		XMLSerializer, RawSerializer, GobSerializer, AutoSerializer} {
+		RegisterSerializer(s)
+	}
+}
+
+// RegisterSerializer 注册序列化方式，codec序列化类型的标识只能注册CodecSerializer，
+// 标识已被其他类型的序列化方式注册时返回ErrSerializerConflict，同类型重复注册会覆盖
+func RegisterSerializer(s Serializer) error {
+	id := s.ID()
+	if _, ok := codecTypeOfID(id); ok {
+		if _, ok := s.(codecSerializer); !ok {
+			return fmt.Errorf("%w: id %#x is reserved for trpc codec", ErrSerializerConflict, id)
+		}
+	}
+	serializers.Lock()
+	defer serializers.Unlock()
+	if old, ok := serializers.m[id]; ok && reflect.TypeOf(old) != reflect.TypeOf(s) {
+		return fmt.Errorf("%w: id %#x is registered by %T", ErrSerializerConflict, id, old)
+	}
+	serializers.m[id] = s
+	return nil
+}
+
+// GetSerializer 根据ID获取序列化方式，未注册返回nil
+func GetSerializer(id byte) Serializer {
+	serializers.RLock()
+	defer serializers.RUnlock()
+	return serializers.m[id]
+}
+
+// serializerByID 根据ID获取序列化方式，codec序列化类型的标识未注册时使用trpc codec中注册的序列化方式
+func serializerByID(id byte) Serializer {
+	if s := GetSerializer(id); s != nil {
+		return s
+	}
+	if t, ok := codecTypeOfID(id); ok && codec.GetSerializer(t) != nil {
+		return CodecSerializer(t)
+	}
+	return nil
+}
+
+// serializerByType 根据trpc codec序列化类型获取序列化方式
+func serializerByType(serializationType int) Serializer {
+	if id, ok := codecSerializerID(serializationType); ok {
+		if s := GetSerializer(id); s != nil {
+			return s
+		}
+	}
+	return CodecSerializer(serializationType)
+}
+
+// codecSerializerID codec序列化类型对应的标识，0~127为类型本身，128~191为0xC0~0xFF，超出范围返回false
+func codecSerializerID(serializationType int) (byte, bool) {
+	switch {
+	case serializationType >= 0 && serializationType < 128:
+		return byte(serializationType), true
+	case serializationType >= 128 && serializationType < 192:
+		return serializerIDCodecHigh + byte(serializationType-128), true
+	}
+	return 0, false
+}
+
+// codecTypeOfID 标识对应的codec序列化类型，lc内置和自定义的标识返回false
+func codecTypeOfID(id byte) (int, bool) {
+	switch {
+	case id < SerializerIDRaw:
+		return int(id), true
+	case id >= serializerIDCodecHigh:
+		return 128 + int(id-serializerIDCodecHigh), true
+	}
+	return 0, false
+}
+
+// codecSerializer trpc codec序列化适配
+type codecSerializer int
+
+// CodecSerializer 将trpc codec序列化方式适配为Serializer，0~127的ID即codec序列化类型，128~191的ID为0xC0~0xFF
+func CodecSerializer(serializationType int) Serializer {
+	return codecSerializer(serializationType)
+}
+
+// ID 返回codec序列化类型对应的标识，超出0~191时返回codec.SerializationTypeUnsupported的标识
+func (s codecSerializer) ID() byte {
+	if id, ok := codecSerializerID(int(s)); ok {
+		return id
+	}
+	id, _ := codecSerializerID(codec.SerializationTypeUnsupported)
+	return id
+}
+
+// Marshal 使用codec序列化
+func (s codecSerializer) Marshal(v interface{}) ([]byte, error) {
+	return codec.Marshal(int(s), v)
+}
+
+// Unmarshal 使用codec反序列化
+func (s codecSerializer) Unmarshal(data []byte, v interface{}) error {
+	return codec.Unmarshal(int(s), data, v)
+}
+
+// rawSerializer 原始二进制、字符串以及基础类型序列化
+type rawSerializer struct{}
+
+// ID 返回SerializerIDRaw
+func (rawSerializer) ID() byte {
+	return SerializerIDRaw
+}
+
+// Marshal []byte、string直接保存，基础类型转化为字符串保存
+func (s rawSerializer) Marshal(val interface{}) ([]byte, error) {
+	switch v := val.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return s.MarshalAppend(nil, val)
+}
+
+// MarshalAppend 同Marshal，结果追加到dst
+func (rawSerializer) MarshalAppend(dst []byte, val interface{}) ([]byte, error) {
+	switch v := val.(type) {
+	case []byte:
+		return append(dst, v...), nil
+	case string:
+		return append(dst, v...), nil
+	}
+	if val == nil || !isScalar(reflect.TypeOf(val)) {
+		return nil, errs.Newf(2004, "lc: value not support type:%s", reflect.TypeOf(val))
+	}
+	b, err := ToString(val)
+	if err != nil {
+		return nil, errs.Newf(2003, "lc: val type:%s ToString error:%s", reflect.TypeOf(val), err.Error())
+	}
+	return append(dst, b...), nil
+}
+
+// Unmarshal 解析到*[]byte、*string以及基础类型指针
+func (rawSerializer) Unmarshal(data []byte, val interface{}) error {
+	switch v := val.(type) {
+	case *[]byte:
+		*v = append((*v)[:0], data...)
+		return nil
+	case *string:
+		*v = string(data)
+		return nil
+	}
+	if err := FromString(string(data), val); err != nil {
+		return errs.Newf(2004, "lc: value type:%s FromString error:%s", reflect.TypeOf(val), err.Error())
+	}
+	return nil
+}
+
+// gobSerializer encoding/gob序列化
+type gobSerializer struct{}
+
+// ID 返回SerializerIDGob
+func (gobSerializer) ID() byte {
+	return SerializerIDGob
+}
+
+// Marshal gob序列化
+func (gobSerializer) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal gob反序列化
+func (gobSerializer) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// autoSerializer 自动选择序列化方式，数据第一个字节记录选择的序列化方式ID
+type autoSerializer struct {
+	// fixed 调用方指定的序列化方式，为nil时根据数据类型选择
+	fixed Serializer
+}
+
+// ID 返回SerializerIDAuto
+func (autoSerializer) ID() byte {
+	return SerializerIDAuto
+}
+
+// Marshal 选择序列化方式并将其ID写入数据头部
+func (s autoSerializer) Marshal(v interface{}) ([]byte, error) {
+	return s.MarshalAppend(nil, v)
+}
+
+// MarshalAppend 同Marshal，结果追加到dst
+func (s autoSerializer) MarshalAppend(dst []byte, v interface{}) ([]byte, error) {
+	ser := s.fixed
+	if ser == nil {
+		ser = chooseSerializer(v)
+	}
+	if cs, ok := ser.(codecSerializer); ok {
+		if _, ok := codecSerializerID(int(cs)); !ok {
+			return nil, errs.Newf(2004, "lc: serialization type:%d not supported by auto serializer", int(cs))
+		}
+	}
+	dst = append(dst, ser.ID())
+	if am, ok := ser.(AppendMarshaler); ok {
+		return am.MarshalAppend(dst, v)
+	}
+	data, err := ser.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, data...), nil
+}
+
+// Unmarshal 使用数据头部记录的序列化方式解析
+func (autoSerializer) Unmarshal(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return ErrInvalidEntry
+	}
+	ser := serializerByID(data[0])
+	if ser == nil || data[0] == SerializerIDAuto {
+		return errs.Newf(2007, "lc: invalid entry, unknown serializer id:%d", data[0])
+	}
+	return ser.Unmarshal(data[1:], v)
+}
+
+// chooseSerializer 根据数据类型选择序列化方式
+func chooseSerializer(v interface{}) Serializer {
+	if _, ok := v.(proto.Message); ok {
+		return PBSerializer
+	}
+	if v != nil && isScalar(reflect.TypeOf(v)) {
+		return RawSerializer
+	}
+	return JSONSerializer
+}
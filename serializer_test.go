@@ -0,0 +1,155 @@
+package lc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/smartystreets/goconvey/convey"
+
+	"trpc.group/trpc-go/trpc-go/codec"
+)
+
+// upperSerializer 测试用自定义序列化方式
+type upperSerializer struct{}
+
+func (upperSerializer) ID() byte { return 0xA0 }
+
+func (upperSerializer) Marshal(v interface{}) ([]byte, error) {
+	return []byte(strings.ToUpper(v.(string))), nil
+}
+
+func (upperSerializer) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*string)) = strings.ToLower(string(data))
+	return nil
+}
+
+// conflictSerializer 测试用标识冲突的序列化方式
+type conflictSerializer struct {
+	upperSerializer
+	id byte
+}
+
+func (s conflictSerializer) ID() byte { return s.id }
+
+// TestSerializer 单测序列化方式
+func TestSerializer(t *testing.T) {
+	convey.Convey("TestSerializer", t, func() {
+		convey.Convey("raw serializer round trip", func() {
+			cache := createCache("test-raw")
+			defer cache.Close()
+			convey.So(cache.Set("int", int64(-12)), convey.ShouldBeNil)
+			var i int64
+			convey.So(cache.Get("int", &i), convey.ShouldBeNil)
+			convey.So(i, convey.ShouldEqual, -12)
+			convey.So(cache.Set("bool", true), convey.ShouldBeNil)
+			var b bool
+			convey.So(cache.Get("bool", &b), convey.ShouldBeNil)
+			convey.So(b, convey.ShouldBeTrue)
+			var str string
+			convey.So(cache.Get("bool", &str), convey.ShouldBeNil)
+			convey.So(str, convey.ShouldEqual, "true")
+			convey.So(cache.Set("struct", &TestParam{}), convey.ShouldNotBeNil)
+
+			// 未指定序列化方式时GetWithLoad命中也使用RawSerializer解析
+			load := func() (interface{}, error) {
+				v := "hello"
+				return &v, nil
+			}
+			for i := 0; i < 2; i++ {
+				var got string
+				convey.So(cache.GetWithLoad(context.TODO(), "load", &got, load), convey.ShouldBeNil)
+				convey.So(got, convey.ShouldEqual, "hello")
+			}
+			var got string
+			_, err := cache.GetWithEntryStatus("load", &got)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(got, convey.ShouldEqual, "hello")
+		})
+
+		convey.Convey("cache serializer", func() {
+			cache := createCache("test-gob", WithSerializer(GobSerializer))
+			defer cache.Close()
+			setData := &TestParam{Name: "gob", Age: 24}
+			convey.So(cache.Set("key", setData), convey.ShouldBeNil)
+			getData := &TestParam{}
+			convey.So(cache.Get("key", getData), convey.ShouldBeNil)
+			convey.So(getData.Age, convey.ShouldEqual, 24)
+
+			getData = &TestParam{}
+			err := cache.GetWithLoad(context.TODO(), "load", getData, func() (interface{}, error) {
+				return setData, nil
+			})
+			convey.So(err, convey.ShouldBeNil)
+			getData = &TestParam{}
+			_, err = cache.GetWithEntryStatus("load", getData)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(getData.Name, convey.ShouldEqual, "gob")
+		})
+
+		convey.Convey("register custom serializer", func() {
+			convey.So(RegisterSerializer(upperSerializer{}), convey.ShouldBeNil)
+			convey.So(GetSerializer(0xA0), convey.ShouldNotBeNil)
+			cache := createCache("test-custom", WithSerializer(GetSerializer(0xA0)))
+			defer cache.Close()
+			convey.So(cache.Set("key", "Hello"), convey.ShouldBeNil)
+			raw, _ := cache.GetBytes("key")
+			convey.So(string(raw), convey.ShouldEqual, "HELLO")
+			var str string
+			convey.So(cache.Get("key", &str), convey.ShouldBeNil)
+			convey.So(str, convey.ShouldEqual, "hello")
+		})
+
+		convey.Convey("serializer id conflict", func() {
+			// codec序列化类型128~191的标识不与lc内置标识冲突
+			convey.So(CodecSerializer(codec.SerializationTypeForm).ID(), convey.ShouldEqual, 0xC1)
+			convey.So(CodecSerializer(codec.SerializationTypeGet).ID(), convey.ShouldEqual, 0xC2)
+			for _, typ := range []int{codec.SerializationTypePB, codec.SerializationTypeUnsupported,
+				codec.SerializationTypeFormData, 191} {
+				id, ok := codecSerializerID(typ)
+				convey.So(ok, convey.ShouldBeTrue)
+				got, ok := codecTypeOfID(id)
+				convey.So(ok, convey.ShouldBeTrue)
+				convey.So(got, convey.ShouldEqual, typ)
+			}
+			_, ok := codecTypeOfID(SerializerIDGob)
+			convey.So(ok, convey.ShouldBeFalse)
+
+			// codec序列化类型的标识和已被其他类型注册的标识拒绝注册
+			convey.So(errors.Is(RegisterSerializer(conflictSerializer{id: 0x05}), ErrSerializerConflict), convey.ShouldBeTrue)
+			convey.So(errors.Is(RegisterSerializer(conflictSerializer{id: 0xC1}), ErrSerializerConflict), convey.ShouldBeTrue)
+			convey.So(errors.Is(RegisterSerializer(conflictSerializer{id: SerializerIDGob}), ErrSerializerConflict),
+				convey.ShouldBeTrue)
+			convey.So(GetSerializer(SerializerIDGob) == GobSerializer, convey.ShouldBeTrue)
+			convey.So(RegisterSerializer(CodecSerializer(codec.SerializationTypeXML)), convey.ShouldBeNil)
+		})
+
+		convey.Convey("auto serialization", func() {
+			cache := createCache("test-auto", WithAutoSerialization())
+			defer cache.Close()
+			ts := &timestamp.Timestamp{Seconds: 1000}
+			convey.So(cache.Set("pb", ts), convey.ShouldBeNil)
+			raw, _ := cache.GetBytes("pb")
+			convey.So(raw[0], convey.ShouldEqual, PBSerializer.ID())
+			getTs := &timestamp.Timestamp{}
+			convey.So(cache.Get("pb", getTs), convey.ShouldBeNil)
+			convey.So(getTs.Seconds, convey.ShouldEqual, 1000)
+
+			convey.So(cache.Set("int", 42), convey.ShouldBeNil)
+			var i int
+			convey.So(cache.Get("int", &i), convey.ShouldBeNil)
+			convey.So(i, convey.ShouldEqual, 42)
+
+			// 写入时指定序列化类型，读取时不指定也能正确解析
+			convey.So(cache.Set("json", &TestParam{Name: "auto"}, codec.SerializationTypeJSON), convey.ShouldBeNil)
+			getData := &TestParam{}
+			convey.So(cache.Get("json", getData), convey.ShouldBeNil)
+			convey.So(getData.Name, convey.ShouldEqual, "auto")
+			getData = &TestParam{}
+			convey.So(cache.Get("json", getData, codec.SerializationTypePB), convey.ShouldBeNil)
+			convey.So(getData.Name, convey.ShouldEqual, "auto")
+		})
+	})
+}
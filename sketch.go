@@ -0,0 +1,175 @@
+package lc
+
+// cmDepth count-min sketch的行数
+const cmDepth = 4
+
+// cmMaxCount 单个计数器的最大值
+const cmMaxCount = 15
+
+// cmSeeds 每行使用的hash种子
+var cmSeeds = [cmDepth]uint64{0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325}
+
+// cmSketch count-min sketch，估算key的访问频率，计数器最大为15，非并发安全
+type cmSketch struct {
+	rows [cmDepth][]uint8
+	mask uint64
+}
+
+// newCMSketch 创建sketch，width向上取整为2的幂
+func newCMSketch(width int) *cmSketch {
+	w := nextPowerOfTwo(width)
+	s := &cmSketch{mask: uint64(w - 1)}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, w)
+	}
+	return s
+}
+
+// index 计算第i行的下标
+func (s *cmSketch) index(hash uint64, i int) uint64 {
+	h := (hash ^ cmSeeds[i]) * 0x9e3779b97f4a7c15
+	return (h ^ (h >> 32)) & s.mask
+}
+
+// increment 频率加1
+func (s *cmSketch) increment(hash uint64) {
+	for i := range s.rows {
+		if idx := s.index(hash, i); s.rows[i][idx] < cmMaxCount {
+			s.rows[i][idx]++
+		}
+	}
+}
+
+// estimate 估算频率，取各行最小值
+func (s *cmSketch) estimate(hash uint64) int {
+	min := uint8(cmMaxCount)
+	for i := range s.rows {
+		if v := s.rows[i][s.index(hash, i)]; v < min {
+			min = v
+		}
+	}
+	return int(min)
+}
+
+// halve 所有计数器减半，用于老化历史频率
+func (s *cmSketch) halve() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+}
+
+// reset 清空计数器
+func (s *cmSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] = 0
+		}
+	}
+}
+
+// doorkeeper 布隆过滤器，过滤只出现一次的key，避免其占用sketch计数器，非并发安全
+type doorkeeper struct {
+	bits []uint64
+	mask uint64
+}
+
+// newDoorkeeper 创建布隆过滤器，bits向上取整为2的幂
+func newDoorkeeper(bits int) *doorkeeper {
+	n := nextPowerOfTwo(bits)
+	if n < 64 {
+		n = 64
+	}
+	return &doorkeeper{bits: make([]uint64, n/64), mask: uint64(n - 1)}
+}
+
+// add 添加hash，返回添加前是否已存在
+func (d *doorkeeper) add(hash uint64) bool {
+	exists := true
+	for i := 0; i < 2; i++ {
+		idx := (hash >> (32 * uint(i))) * 0x9e3779b97f4a7c15 & d.mask
+		word, bit := idx/64, uint64(1)<<(idx%64)
+		if d.bits[word]&bit == 0 {
+			exists = false
+			d.bits[word] |= bit
+		}
+	}
+	return exists
+}
+
+// contains 判断hash是否存在
+func (d *doorkeeper) contains(hash uint64) bool {
+	for i := 0; i < 2; i++ {
+		idx := (hash >> (32 * uint(i))) * 0x9e3779b97f4a7c15 & d.mask
+		if d.bits[idx/64]&(uint64(1)<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// reset 清空
+func (d *doorkeeper) reset() {
+	for i := range d.bits {
+		d.bits[i] = 0
+	}
+}
+
+// tinyLFU 基于doorkeeper和count-min sketch的频率统计，每sampleSize次访问老化一次，非并发安全
+type tinyLFU struct {
+	sketch     *cmSketch
+	door       *doorkeeper
+	additions  int
+	sampleSize int
+}
+
+// newTinyLFU 创建频率统计，counters为预估的key数量
+func newTinyLFU(counters int) *tinyLFU {
+	if counters < 16 {
+		counters = 16
+	}
+	return &tinyLFU{
+		sketch:     newCMSketch(counters),
+		door:       newDoorkeeper(counters * 8),
+		sampleSize: counters * 10,
+	}
+}
+
+// increment 记录一次访问，第一次访问只记录在doorkeeper中
+func (t *tinyLFU) increment(hash uint64) {
+	t.additions++
+	if t.additions >= t.sampleSize {
+		t.sketch.halve()
+		t.door.reset()
+		t.additions = 0
+	}
+	if t.door.add(hash) {
+		t.sketch.increment(hash)
+	}
+}
+
+// estimate 估算访问频率
+func (t *tinyLFU) estimate(hash uint64) int {
+	n := t.sketch.estimate(hash)
+	if t.door.contains(hash) {
+		n++
+	}
+	return n
+}
+
+// reset 清空频率统计
+func (t *tinyLFU) reset() {
+	t.sketch.reset()
+	t.door.reset()
+	t.additions = 0
+}
+
+// nextPowerOfTwo 向上取整为2的幂
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
@@ -0,0 +1,155 @@
+package lc
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/allegro/bigcache/v3"
+)
+
+// EvictionPolicy 内存达到上限时的淘汰策略
+type EvictionPolicy string
+
+// 淘汰策略定义
+const (
+	// EvictionFIFO 淘汰最早写入的数据，使用bigcache存储，默认策略
+	EvictionFIFO EvictionPolicy = "fifo"
+	// EvictionLRU 淘汰最久未访问的数据
+	EvictionLRU EvictionPolicy = "lru"
+	// EvictionTinyLFU W-TinyLFU，综合访问频率和时间淘汰，适合热点数据长期存在的场景
+	EvictionTinyLFU EvictionPolicy = "tinylfu"
+)
+
+// store 数据存储，保存的是带头部的数据
+type store interface {
+	// Get 获取数据，不存在返回bigcache.ErrEntryNotFound
+	Get(key string) ([]byte, error)
+	// Peek 获取数据，不计入命中统计和单个key的命中次数，不影响淘汰顺序，不存在返回bigcache.ErrEntryNotFound
+	Peek(key string) ([]byte, error)
+	// Set 保存数据，实现需要拷贝entry，调用方会复用entry的内存
+	Set(key string, entry []byte) error
+	// Delete 删除数据，不存在返回bigcache.ErrEntryNotFound
+	Delete(key string) error
+	// Reset 清空数据
+	Reset() error
+	// Len 数据条数
+	Len() int
+	// Capacity 占用字节数
+	Capacity() int
+	// Stats 命中统计
+	Stats() bigcache.Stats
+	// KeyMetadata 单个key的命中次数，需要开启StatsEnabled
+	KeyMetadata(key string) bigcache.Metadata
+	// Iterator 遍历数据
+	Iterator() storeIterator
+	// Close 关闭存储，退出清理协程
+	Close() error
+}
+
+// storeIterator 遍历存储的迭代器
+type storeIterator interface {
+	// SetNext 移动到下一条数据，没有更多数据时返回false
+	SetNext() bool
+	// Value 返回当前数据
+	Value() (key string, hash uint64, entry []byte, err error)
+}
+
+// bigcacheStore bigcache存储
+// bigcache没有不计入统计的读取，Peek的命中和未命中记录下来，在Stats和KeyMetadata中扣除
+type bigcacheStore struct {
+	*bigcache.BigCache
+	peekHits     int64 // Peek命中次数, 原子读写
+	peekMisses   int64 // Peek未命中次数, 原子读写
+	statsEnabled bool
+	mu           sync.Mutex
+	peekKeys     map[uint64]uint32 // 开启StatsEnabled时每个key被Peek命中的次数, 数据删除或淘汰时清除
+}
+
+// newBigcacheStore 创建bigcache存储
+func newBigcacheStore(cfg bigcache.Config) (*bigcacheStore, error) {
+	s := &bigcacheStore{statsEnabled: cfg.StatsEnabled}
+	if s.statsEnabled {
+		// bigcache删除或淘汰数据时清除单个key的命中次数，同步清除Peek的次数
+		s.peekKeys = make(map[uint64]uint32)
+		cfg.OnRemoveWithReason = s.onRemove
+	}
+	bc, err := bigcache.NewBigCache(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.BigCache = bc
+	return s, nil
+}
+
+// Peek 获取数据，不计入统计
+func (s *bigcacheStore) Peek(key string) ([]byte, error) {
+	entry, err := s.BigCache.Get(key)
+	if err == bigcache.ErrEntryNotFound {
+		atomic.AddInt64(&s.peekMisses, 1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt64(&s.peekHits, 1)
+	if s.statsEnabled {
+		s.mu.Lock()
+		s.peekKeys[hashKey(key)]++
+		s.mu.Unlock()
+	}
+	return entry, nil
+}
+
+// onRemove bigcache删除或淘汰数据的回调
+func (s *bigcacheStore) onRemove(key string, _ []byte, _ bigcache.RemoveReason) {
+	s.mu.Lock()
+	delete(s.peekKeys, hashKey(key))
+	s.mu.Unlock()
+}
+
+// Stats 命中统计，不含Peek
+func (s *bigcacheStore) Stats() bigcache.Stats {
+	stats := s.BigCache.Stats()
+	stats.Hits -= atomic.LoadInt64(&s.peekHits)
+	stats.Misses -= atomic.LoadInt64(&s.peekMisses)
+	return stats
+}
+
+// KeyMetadata 单个key的命中次数，不含Peek
+func (s *bigcacheStore) KeyMetadata(key string) bigcache.Metadata {
+	meta := s.BigCache.KeyMetadata(key)
+	if !s.statsEnabled {
+		return meta
+	}
+	s.mu.Lock()
+	n := s.peekKeys[hashKey(key)]
+	s.mu.Unlock()
+	if n > meta.RequestCount {
+		n = meta.RequestCount
+	}
+	meta.RequestCount -= n
+	return meta
+}
+
+// Iterator 遍历数据
+func (s *bigcacheStore) Iterator() storeIterator {
+	return bigcacheIterator{s.BigCache.Iterator()}
+}
+
+// bigcacheIterator bigcache迭代器适配
+type bigcacheIterator struct {
+	it *bigcache.EntryInfoIterator
+}
+
+// SetNext 移动到下一条数据
+func (it bigcacheIterator) SetNext() bool {
+	return it.it.SetNext()
+}
+
+// Value 返回当前数据
+func (it bigcacheIterator) Value() (string, uint64, []byte, error) {
+	info, err := it.it.Value()
+	if err != nil {
+		return "", 0, nil, err
+	}
+	return info.Key(), info.Hash(), info.Value(), nil
+}
@@ -0,0 +1,443 @@
+package lc
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+
+	"trpc.group/trpc-go/trpc-go/errs"
+)
+
+// ErrEntryTooBig 数据超过单个分片的最大内存
+var ErrEntryTooBig = errs.New(2009, "lc: entry is bigger than max shard size")
+
+// memEntry 内存存储中的一条数据
+type memEntry struct {
+	key   string
+	hash  uint64
+	entry []byte
+	cost  int           // 占用字节数: key + 数据
+	elem  *list.Element // 所在链表中的位置
+	seg   uint8         // 所在分段, 仅W-TinyLFU使用
+	hits  uint32        // 命中次数, 仅开启StatsEnabled时统计
+}
+
+// policy 淘汰策略，由分片加锁调用，非并发安全
+type policy interface {
+	// add 新增数据，返回需要淘汰的数据
+	add(e *memEntry) []*memEntry
+	// update 数据重新写入，delta为占用字节数的变化，返回需要淘汰的数据
+	update(e *memEntry, delta int) []*memEntry
+	// access 访问数据
+	access(e *memEntry)
+	// miss 访问不存在的数据
+	miss(hash uint64)
+	// remove 删除数据
+	remove(e *memEntry)
+	// reset 清空
+	reset()
+	// resize 调整容量，返回需要淘汰的数据
+	resize(capacity int) []*memEntry
+}
+
+// memShard 内存存储分片
+type memShard struct {
+	mu     sync.Mutex
+	items  map[string]*memEntry
+	cost   int
+	policy policy
+	stats  bigcache.Stats
+}
+
+// memStore 支持LRU/W-TinyLFU淘汰策略的内存存储，按字节数限制内存
+type memStore struct {
+	shards    []*memShard
+	mask      uint64
+	shardCost int64                   // 单个分片最大字节数, 原子读写
+	keyStats  bool                    // 是否统计单个key的命中次数
+	isExpired func(entry []byte) bool // 判断数据是否过期, 用于定期清理
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newMemStore 创建内存存储
+func newMemStore(cfg *Config, isExpired func(entry []byte) bool) *memStore {
+	shards := cfg.Shards
+	if shards <= 0 || shards&(shards-1) != 0 {
+		panic("lc: shards number must be power of two")
+	}
+	maxCost := cfg.HardMaxCacheSize * 1024 * 1024
+	if maxCost <= 0 {
+		maxCost = cfg.MaxEntriesInWindow * cfg.MaxEntrySize
+	}
+	s := &memStore{
+		shards:    make([]*memShard, shards),
+		mask:      uint64(shards - 1),
+		shardCost: int64(maxCost / shards),
+		keyStats:  cfg.StatsEnabled,
+		isExpired: isExpired,
+		done:      make(chan struct{}),
+	}
+	counters := cfg.MaxEntriesInWindow / shards
+	for i := range s.shards {
+		s.shards[i] = &memShard{items: make(map[string]*memEntry)}
+		if cfg.EvictionPolicy == EvictionTinyLFU {
+			s.shards[i].policy = newTinyLFUPolicy(maxCost/shards, counters)
+		} else {
+			s.shards[i].policy = newLRUPolicy(maxCost / shards)
+		}
+	}
+	if cfg.CleanWindow > 0 {
+		go s.cleanUp(cfg.Clock, cfg.CleanWindow)
+	}
+	return s
+}
+
+// shard 获取key所在的分片
+func (s *memStore) shard(hash uint64) *memShard {
+	return s.shards[hash&s.mask]
+}
+
+// Get 获取数据
+func (s *memStore) Get(key string) ([]byte, error) {
+	hash := hashKey(key)
+	sh := s.shard(hash)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	e, ok := sh.items[key]
+	if !ok {
+		sh.stats.Misses++
+		sh.policy.miss(hash)
+		return nil, bigcache.ErrEntryNotFound
+	}
+	sh.stats.Hits++
+	if s.keyStats {
+		e.hits++
+	}
+	sh.policy.access(e)
+	return append([]byte(nil), e.entry...), nil
+}
+
+// Peek 获取数据，不计入统计，不影响淘汰顺序和访问频率
+func (s *memStore) Peek(key string) ([]byte, error) {
+	sh := s.shard(hashKey(key))
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	e, ok := sh.items[key]
+	if !ok {
+		return nil, bigcache.ErrEntryNotFound
+	}
+	return append([]byte(nil), e.entry...), nil
+}
+
+// access 获取数据并更新淘汰顺序和访问频率，不计入命中统计，用于读取分块
+func (s *memStore) access(key string) ([]byte, error) {
+	sh := s.shard(hashKey(key))
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	e, ok := sh.items[key]
+	if !ok {
+		return nil, bigcache.ErrEntryNotFound
+	}
+	sh.policy.access(e)
+	return append([]byte(nil), e.entry...), nil
+}
+
+// View 持有分片锁在fn中访问数据，不拷贝，fn不能持有entry，不存在返回bigcache.ErrEntryNotFound
+func (s *memStore) View(key string, fn func(entry []byte) error) error {
+	hash := hashKey(key)
+	sh := s.shard(hash)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	e, ok := sh.items[key]
+	if !ok {
+		sh.stats.Misses++
+		sh.policy.miss(hash)
+		return bigcache.ErrEntryNotFound
+	}
+	sh.stats.Hits++
+	if s.keyStats {
+		e.hits++
+	}
+	sh.policy.access(e)
+	return fn(e.entry)
+}
+
+// Set 保存数据
+func (s *memStore) Set(key string, entry []byte) error {
+	cost := len(key) + len(entry)
+	if int64(cost) > atomic.LoadInt64(&s.shardCost) {
+		return ErrEntryTooBig
+	}
+	hash := hashKey(key)
+	sh := s.shard(hash)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if e, ok := sh.items[key]; ok {
+		delta := cost - e.cost
+		e.entry = append(e.entry[:0], entry...)
+		e.cost = cost
+		sh.cost += delta
+		sh.evict(sh.policy.update(e, delta))
+		return nil
+	}
+	e := &memEntry{key: key, hash: hash, entry: append([]byte(nil), entry...), cost: cost}
+	sh.items[key] = e
+	sh.cost += cost
+	sh.evict(sh.policy.add(e))
+	return nil
+}
+
+// evict 删除被淘汰的数据，淘汰策略已将其从链表中删除
+func (sh *memShard) evict(evicted []*memEntry) {
+	for _, e := range evicted {
+		delete(sh.items, e.key)
+		sh.cost -= e.cost
+	}
+}
+
+// Delete 删除数据
+func (s *memStore) Delete(key string) error {
+	sh := s.shard(hashKey(key))
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	e, ok := sh.items[key]
+	if !ok {
+		sh.stats.DelMisses++
+		return bigcache.ErrEntryNotFound
+	}
+	sh.stats.DelHits++
+	sh.policy.remove(e)
+	delete(sh.items, key)
+	sh.cost -= e.cost
+	return nil
+}
+
+// Reset 清空数据
+func (s *memStore) Reset() error {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		sh.items = make(map[string]*memEntry)
+		sh.cost = 0
+		sh.policy.reset()
+		sh.mu.Unlock()
+	}
+	return nil
+}
+
+// Len 数据条数
+func (s *memStore) Len() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		n += len(sh.items)
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+// Capacity 占用字节数
+func (s *memStore) Capacity() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		n += sh.cost
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+// Stats 命中统计
+func (s *memStore) Stats() bigcache.Stats {
+	var stats bigcache.Stats
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		stats.Hits += sh.stats.Hits
+		stats.Misses += sh.stats.Misses
+		stats.DelHits += sh.stats.DelHits
+		stats.DelMisses += sh.stats.DelMisses
+		sh.mu.Unlock()
+	}
+	return stats
+}
+
+// setMaxCost 调整最大字节数，超出的数据按淘汰策略淘汰
+func (s *memStore) setMaxCost(maxCost int) {
+	shardCost := maxCost / len(s.shards)
+	atomic.StoreInt64(&s.shardCost, int64(shardCost))
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		sh.evict(sh.policy.resize(shardCost))
+		sh.mu.Unlock()
+	}
+}
+
+// shrink 按淘汰策略淘汰fraction比例的数据，容量不变
+func (s *memStore) shrink(fraction float64) {
+	shardCost := int(atomic.LoadInt64(&s.shardCost))
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		sh.evict(sh.policy.resize(int(float64(sh.cost) * (1 - fraction))))
+		sh.evict(sh.policy.resize(shardCost))
+		sh.mu.Unlock()
+	}
+}
+
+// maxCost 最大字节数
+func (s *memStore) maxCost() int {
+	return int(atomic.LoadInt64(&s.shardCost)) * len(s.shards)
+}
+
+// KeyMetadata 单个key的命中次数
+func (s *memStore) KeyMetadata(key string) bigcache.Metadata {
+	sh := s.shard(hashKey(key))
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if e, ok := sh.items[key]; ok {
+		return bigcache.Metadata{RequestCount: e.hits}
+	}
+	return bigcache.Metadata{}
+}
+
+// Close 退出清理协程
+func (s *memStore) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return nil
+}
+
+// cleanUp 按时钟定期删除过期数据
+func (s *memStore) cleanUp(clock Clock, window time.Duration) {
+	runEvery(clock, window, s.done, s.removeExpired)
+}
+
+// removeExpired 删除所有过期数据
+func (s *memStore) removeExpired() {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for key, e := range sh.items {
+			if s.isExpired(e.entry) {
+				sh.policy.remove(e)
+				delete(sh.items, key)
+				sh.cost -= e.cost
+			}
+		}
+		sh.mu.Unlock()
+	}
+}
+
+// Iterator 遍历数据，遍历创建时存在的key，遍历过程中被删除的key会跳过
+func (s *memStore) Iterator() storeIterator {
+	it := &memIterator{store: s}
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for key := range sh.items {
+			it.keys = append(it.keys, key)
+		}
+		sh.mu.Unlock()
+	}
+	return it
+}
+
+// memIterator 内存存储迭代器
+type memIterator struct {
+	store *memStore
+	keys  []string
+	next  int
+	key   string
+	hash  uint64
+	entry []byte
+}
+
+// SetNext 移动到下一条仍然存在的数据
+func (it *memIterator) SetNext() bool {
+	for it.next < len(it.keys) {
+		key := it.keys[it.next]
+		it.next++
+		hash := hashKey(key)
+		sh := it.store.shard(hash)
+		sh.mu.Lock()
+		e, ok := sh.items[key]
+		if ok {
+			it.key, it.hash, it.entry = key, hash, append([]byte(nil), e.entry...)
+		}
+		sh.mu.Unlock()
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Value 返回当前数据
+func (it *memIterator) Value() (string, uint64, []byte, error) {
+	if it.entry == nil {
+		return "", 0, nil, bigcache.ErrInvalidIteratorState
+	}
+	return it.key, it.hash, it.entry, nil
+}
+
+// lruPolicy LRU淘汰策略
+type lruPolicy struct {
+	ll       *list.List
+	cost     int
+	capacity int
+}
+
+// newLRUPolicy 创建LRU淘汰策略，capacity为最大字节数
+func newLRUPolicy(capacity int) *lruPolicy {
+	return &lruPolicy{ll: list.New(), capacity: capacity}
+}
+
+// add 新数据放到链表头部，超出容量时从尾部淘汰
+func (p *lruPolicy) add(e *memEntry) []*memEntry {
+	e.elem = p.ll.PushFront(e)
+	p.cost += e.cost
+	return p.evict()
+}
+
+// update 重新写入的数据移到链表头部
+func (p *lruPolicy) update(e *memEntry, delta int) []*memEntry {
+	p.cost += delta
+	p.ll.MoveToFront(e.elem)
+	return p.evict()
+}
+
+// access 访问的数据移到链表头部
+func (p *lruPolicy) access(e *memEntry) {
+	p.ll.MoveToFront(e.elem)
+}
+
+// miss LRU不关心未命中
+func (p *lruPolicy) miss(uint64) {}
+
+// remove 从链表删除
+func (p *lruPolicy) remove(e *memEntry) {
+	p.ll.Remove(e.elem)
+	p.cost -= e.cost
+}
+
+// reset 清空
+func (p *lruPolicy) reset() {
+	p.ll.Init()
+	p.cost = 0
+}
+
+// resize 调整容量
+func (p *lruPolicy) resize(capacity int) []*memEntry {
+	p.capacity = capacity
+	return p.evict()
+}
+
+// evict 从尾部淘汰直到不超过容量
+func (p *lruPolicy) evict() []*memEntry {
+	var evicted []*memEntry
+	for p.cost > p.capacity && p.ll.Len() > 0 {
+		e := p.ll.Remove(p.ll.Back()).(*memEntry)
+		p.cost -= e.cost
+		evicted = append(evicted, e)
+	}
+	return evicted
+}
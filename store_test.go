@@ -0,0 +1,68 @@
+package lc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestEvictionPolicy 单测LRU和W-TinyLFU淘汰策略
+func TestEvictionPolicy(t *testing.T) {
+	value := make([]byte, 100*1024)
+	convey.Convey("TestEvictionPolicy", t, func() {
+		convey.Convey("lru", func() {
+			cache := createCache("test-lru", WithShards(1), WithHardMaxCacheSize(1),
+				WithEvictionPolicy(EvictionLRU))
+			defer cache.Close()
+			for i := 0; i < 9; i++ {
+				convey.So(cache.Set(fmt.Sprintf("k%d", i), value), convey.ShouldBeNil)
+			}
+			convey.So(cache.Len(), convey.ShouldEqual, 9)
+			// k0最近被访问过，淘汰k1
+			_, err := cache.GetBytes("k0")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(cache.Set("k9", value), convey.ShouldBeNil)
+			convey.So(cache.Set("k10", value), convey.ShouldBeNil)
+			_, err = cache.GetBytes("k0")
+			convey.So(err, convey.ShouldBeNil)
+			_, err = cache.GetBytes("k1")
+			convey.So(err, convey.ShouldNotBeNil)
+			convey.So(cache.Capacity(), convey.ShouldBeLessThanOrEqualTo, 1024*1024)
+
+			convey.So(cache.Set("big", make([]byte, 2*1024*1024)), convey.ShouldEqual, ErrEntryTooBig)
+
+			n := 0
+			it := cache.Iterator()
+			for it.SetNext() {
+				info, err := it.Value()
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(len(info.Value()), convey.ShouldEqual, len(value))
+				n++
+			}
+			convey.So(n, convey.ShouldEqual, cache.Len())
+		})
+		convey.Convey("tinylfu", func() {
+			cache := createCache("test-tinylfu", WithShards(1), WithHardMaxCacheSize(1),
+				WithEvictionPolicy(EvictionTinyLFU))
+			defer cache.Close()
+			for i := 0; i < 4; i++ {
+				key := fmt.Sprintf("hot%d", i)
+				convey.So(cache.Set(key, value), convey.ShouldBeNil)
+				for j := 0; j < 5; j++ {
+					_, err := cache.GetBytes(key)
+					convey.So(err, convey.ShouldBeNil)
+				}
+			}
+			// 大量只访问一次的数据不会挤掉热点数据
+			for i := 0; i < 50; i++ {
+				convey.So(cache.Set(fmt.Sprintf("cold%d", i), value), convey.ShouldBeNil)
+			}
+			for i := 0; i < 4; i++ {
+				_, err := cache.GetBytes(fmt.Sprintf("hot%d", i))
+				convey.So(err, convey.ShouldBeNil)
+			}
+			convey.So(cache.Capacity(), convey.ShouldBeLessThanOrEqualTo, 1024*1024)
+		})
+	})
+}
@@ -0,0 +1,93 @@
+package lc
+
+import (
+	"context"
+	"strconv"
+)
+
+// span名称
+const (
+	SpanGetWithLoad = "lc.GetWithLoad" // GetWithLoad整体调用
+	SpanLoad        = "lc.Load"        // 穿透函数调用
+	SpanMarshal     = "lc.Marshal"     // 序列化
+	SpanUnmarshal   = "lc.Unmarshal"   // 反序列化
+)
+
+// span标签名称
+const (
+	AttrCacheName = "lc.cache_name" // cache名称
+	AttrKeyHash   = "lc.key_hash"   // key的hash值，避免key明文上报
+	AttrStatus    = "lc.status"     // 缓存状态: hit/miss/expired
+	AttrFallback  = "lc.fallback"   // 是否使用了过期数据兜底
+	AttrSize      = "lc.size"       // 序列化后的字节数
+)
+
+// 缓存状态
+const (
+	StatusHit     = "hit"     // 命中缓存
+	StatusMiss    = "miss"    // 未命中缓存
+	StatusExpired = "expired" // 数据过期但未清除
+)
+
+// Attribute span标签
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span 链路追踪中的一个span
+type Span interface {
+	// SetAttributes 设置span标签
+	SetAttributes(attrs ...Attribute)
+	// RecordError 记录错误
+	RecordError(err error)
+	// End 结束span
+	End()
+}
+
+// Tracer 链路追踪接口，可对接opentelemetry等实现，参考oteltracer包
+type Tracer interface {
+	// Start 以ctx中的span为父span创建新span，返回携带新span的ctx
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+// noopTracer 默认不做任何追踪
+type noopTracer struct{}
+
+// noopSpan 空span
+type noopSpan struct{}
+
+// Start 返回原ctx和空span
+func (noopTracer) Start(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+// SetAttributes 空实现
+func (noopSpan) SetAttributes(...Attribute) {}
+
+// RecordError 空实现
+func (noopSpan) RecordError(error) {}
+
+// End 空实现
+func (noopSpan) End() {}
+
+// startSpan 创建span并设置cache名称和key hash标签
+func (c *Cache) startSpan(ctx context.Context, spanName, key string) (context.Context, Span) {
+	if !c.tracing() {
+		return ctx, noopSpan{}
+	}
+	ctx, span := c.tracer.Start(ctx, spanName)
+	span.SetAttributes(Attribute{Key: AttrCacheName, Value: c.name}, Attribute{Key: AttrKeyHash, Value: keyHash(key)})
+	return ctx, span
+}
+
+// tracing 是否开启了链路追踪，未开启时不生成span属性，避免读写路径上的内存分配
+func (c *Cache) tracing() bool {
+	_, noop := c.tracer.(noopTracer)
+	return !noop
+}
+
+// keyHash 计算key的fnv64a hash
+func keyHash(key string) string {
+	return strconv.FormatUint(hashKey(key), 16)
+}
@@ -0,0 +1,93 @@
+package lc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+
+	"trpc.group/trpc-go/trpc-go/codec"
+)
+
+// recordTracer 记录span的tracer
+type recordTracer struct {
+	sync.Mutex
+	spans []*recordSpan
+}
+
+// recordSpan 记录标签的span
+type recordSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (t *recordTracer) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	t.Lock()
+	defer t.Unlock()
+	s := &recordSpan{name: spanName, attrs: make(map[string]interface{})}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func (t *recordTracer) find(name string) []*recordSpan {
+	t.Lock()
+	defer t.Unlock()
+	var res []*recordSpan
+	for _, s := range t.spans {
+		if s.name == name {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func (s *recordSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordSpan) RecordError(err error) { s.err = err }
+
+func (s *recordSpan) End() { s.ended = true }
+
+// TestTracer 单测链路追踪
+func TestTracer(t *testing.T) {
+	convey.Convey("TestTracer", t, func() {
+		tracer := &recordTracer{}
+		cache := createCache("test-tracer", WithTracer(tracer))
+		defer cache.Close()
+
+		getData := &TestParam{}
+		loadFunc := func() (interface{}, error) {
+			return &TestParam{Name: "tracer"}, nil
+		}
+		err := cache.GetWithLoad(context.TODO(), "key", getData, loadFunc, codec.SerializationTypeJSON)
+		convey.So(err, convey.ShouldBeNil)
+		err = cache.GetWithLoad(context.TODO(), "key", getData, loadFunc, codec.SerializationTypeJSON)
+		convey.So(err, convey.ShouldBeNil)
+
+		spans := tracer.find(SpanGetWithLoad)
+		convey.So(len(spans), convey.ShouldEqual, 2)
+		convey.So(spans[0].attrs[AttrStatus], convey.ShouldEqual, StatusMiss)
+		convey.So(spans[1].attrs[AttrStatus], convey.ShouldEqual, StatusHit)
+		convey.So(spans[0].attrs[AttrCacheName], convey.ShouldEqual, "test-tracer")
+		convey.So(spans[0].attrs[AttrKeyHash], convey.ShouldEqual, keyHash("key"))
+		convey.So(spans[0].ended, convey.ShouldBeTrue)
+		convey.So(len(tracer.find(SpanLoad)), convey.ShouldEqual, 1)
+		convey.So(len(tracer.find(SpanMarshal)), convey.ShouldEqual, 1)
+		convey.So(len(tracer.find(SpanUnmarshal)), convey.ShouldEqual, 1)
+
+		err = cache.GetWithLoad(context.TODO(), "fail", getData, func() (interface{}, error) {
+			return nil, errors.New("load fail")
+		}, codec.SerializationTypeJSON)
+		convey.So(err, convey.ShouldNotBeNil)
+		spans = tracer.find(SpanGetWithLoad)
+		convey.So(spans[2].err, convey.ShouldNotBeNil)
+		convey.So(spans[2].attrs[AttrFallback], convey.ShouldEqual, false)
+	})
+}
@@ -0,0 +1,104 @@
+package lc
+
+import (
+	"context"
+	"time"
+)
+
+// scalarSerializer 基础类型的序列化方式，自动序列化模式下同样记录序列化方式，保证Get也能正确解析
+func (c *Cache) scalarSerializer() Serializer {
+	if _, ok := c.ser.(autoSerializer); ok {
+		return autoSerializer{fixed: RawSerializer}
+	}
+	return RawSerializer
+}
+
+// setScalar 以文本形式保存基础类型
+func (c *Cache) setScalar(key string, val interface{}) error {
+	_, err := c.set(context.Background(), key, val, c.scalarSerializer(), false)
+	return err
+}
+
+// GetString 获取字符串，不存在返回ErrRecordNotFound
+func (c *Cache) GetString(key string) (string, error) {
+	var v string
+	err := c.get(key, &v, c.scalarSerializer())
+	return v, err
+}
+
+// SetString 保存字符串
+func (c *Cache) SetString(key string, val string) error {
+	return c.setScalar(key, val)
+}
+
+// GetInt64 获取int64，不存在返回ErrRecordNotFound
+func (c *Cache) GetInt64(key string) (int64, error) {
+	var v int64
+	err := c.get(key, &v, c.scalarSerializer())
+	return v, err
+}
+
+// SetInt64 保存int64
+func (c *Cache) SetInt64(key string, val int64) error {
+	return c.setScalar(key, val)
+}
+
+// GetUint64 获取uint64，不存在返回ErrRecordNotFound
+func (c *Cache) GetUint64(key string) (uint64, error) {
+	var v uint64
+	err := c.get(key, &v, c.scalarSerializer())
+	return v, err
+}
+
+// SetUint64 保存uint64
+func (c *Cache) SetUint64(key string, val uint64) error {
+	return c.setScalar(key, val)
+}
+
+// GetFloat64 获取float64，不存在返回ErrRecordNotFound
+func (c *Cache) GetFloat64(key string) (float64, error) {
+	var v float64
+	err := c.get(key, &v, c.scalarSerializer())
+	return v, err
+}
+
+// SetFloat64 保存float64
+func (c *Cache) SetFloat64(key string, val float64) error {
+	return c.setScalar(key, val)
+}
+
+// GetBool 获取bool，不存在返回ErrRecordNotFound
+func (c *Cache) GetBool(key string) (bool, error) {
+	var v bool
+	err := c.get(key, &v, c.scalarSerializer())
+	return v, err
+}
+
+// SetBool 保存bool
+func (c *Cache) SetBool(key string, val bool) error {
+	return c.setScalar(key, val)
+}
+
+// GetDuration 获取time.Duration，不存在返回ErrRecordNotFound
+func (c *Cache) GetDuration(key string) (time.Duration, error) {
+	var v time.Duration
+	err := c.get(key, &v, c.scalarSerializer())
+	return v, err
+}
+
+// SetDuration 保存time.Duration
+func (c *Cache) SetDuration(key string, val time.Duration) error {
+	return c.setScalar(key, val)
+}
+
+// GetTime 获取time.Time，不存在返回ErrRecordNotFound
+func (c *Cache) GetTime(key string) (time.Time, error) {
+	var v time.Time
+	err := c.get(key, &v, c.scalarSerializer())
+	return v, err
+}
+
+// SetTime 保存time.Time，精度到纳秒，保留时区偏移
+func (c *Cache) SetTime(key string, val time.Time) error {
+	return c.setScalar(key, val)
+}
@@ -0,0 +1,59 @@
+package lc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestTypedAccessors 单测基础类型读写
+func TestTypedAccessors(t *testing.T) {
+	convey.Convey("TestTypedAccessors", t, func() {
+		for _, opts := range [][]Option{nil, {WithAutoSerialization()}} {
+			cache := createCache("test-typed", opts...)
+
+			convey.So(cache.SetString("string", "lc"), convey.ShouldBeNil)
+			s, err := cache.GetString("string")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(s, convey.ShouldEqual, "lc")
+
+			convey.So(cache.SetInt64("int64", -64), convey.ShouldBeNil)
+			i, err := cache.GetInt64("int64")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(i, convey.ShouldEqual, -64)
+
+			convey.So(cache.SetUint64("uint64", 64), convey.ShouldBeNil)
+			u, err := cache.GetUint64("uint64")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(u, convey.ShouldEqual, 64)
+
+			convey.So(cache.SetFloat64("float64", 0.1), convey.ShouldBeNil)
+			f, err := cache.GetFloat64("float64")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(f, convey.ShouldEqual, 0.1)
+
+			convey.So(cache.SetBool("bool", true), convey.ShouldBeNil)
+			b, err := cache.GetBool("bool")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(b, convey.ShouldBeTrue)
+
+			convey.So(cache.SetDuration("duration", 1500*time.Millisecond), convey.ShouldBeNil)
+			d, err := cache.GetDuration("duration")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(d, convey.ShouldEqual, 1500*time.Millisecond)
+
+			now := time.Now()
+			convey.So(cache.SetTime("time", now), convey.ShouldBeNil)
+			tm, err := cache.GetTime("time")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(tm.Equal(now), convey.ShouldBeTrue)
+
+			_, err = cache.GetBool("string")
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = cache.GetInt64("not-exist")
+			convey.So(err, convey.ShouldEqual, ErrRecordNotFound)
+			cache.Close()
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package lc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestView 单测View/GetInto
+func TestView(t *testing.T) {
+	convey.Convey("TestView", t, func() {
+		for _, policy := range []EvictionPolicy{EvictionFIFO, EvictionLRU} {
+			cache := createCache("test-view", WithEvictionPolicy(policy), WithChunking(1024))
+			convey.So(cache.Set("key", "value"), convey.ShouldBeNil)
+			var got string
+			convey.So(cache.View("key", func(data []byte) error {
+				got = string(data)
+				return nil
+			}), convey.ShouldBeNil)
+			convey.So(got, convey.ShouldEqual, "value")
+			convey.So(cache.View("not-exist", func([]byte) error { return nil }), convey.ShouldEqual,
+				ErrRecordNotFound)
+
+			buf := make([]byte, 0, 16)
+			buf, err := cache.GetInto("key", append(buf, "prefix:"...))
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(string(buf), convey.ShouldEqual, "prefix:value")
+			// buf有容量时追加到buf，复用buf的内存
+			reused, err := cache.GetInto("key", buf[:0])
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(string(reused), convey.ShouldEqual, "value")
+			convey.So(&reused[0], convey.ShouldEqual, &buf[0])
+			// buf容量为0时fifo存储直接返回bigcache拷贝出的数据
+			data, err := cache.GetInto("key", nil)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(string(data), convey.ShouldEqual, "value")
+			_, err = cache.GetInto("not-exist", nil)
+			convey.So(err, convey.ShouldEqual, ErrRecordNotFound)
+
+			// 分块保存的数据
+			large := bytes.Repeat([]byte("x"), 4000)
+			convey.So(cache.Set("large", large), convey.ShouldBeNil)
+			buf, err = cache.GetInto("large", buf[:0])
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(bytes.Equal(buf, large), convey.ShouldBeTrue)
+			cache.Close()
+		}
+	})
+}
+
+// TestPooledSet 单测Set复用缓冲区以及GetInto不分配内存
+func TestPooledSet(t *testing.T) {
+	convey.Convey("TestPooledSet", t, func() {
+		cache := createCache("test-pooled-set", WithEvictionPolicy(EvictionLRU))
+		defer cache.Close()
+		value := []byte("value")
+		convey.So(cache.Set("a", value), convey.ShouldBeNil)
+		convey.So(cache.SetInt64("b", 42), convey.ShouldBeNil)
+		// 缓冲区复用后不影响已保存的数据
+		s, err := cache.GetBytes("a")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(string(s), convey.ShouldEqual, "value")
+		n, err := cache.GetInt64("b")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(n, convey.ShouldEqual, 42)
+
+		// lru/tinylfu存储读取不拷贝数据
+		buf := make([]byte, 0, 64)
+		allocs := testing.AllocsPerRun(100, func() {
+			buf, _ = cache.GetInto("a", buf[:0])
+		})
+		convey.So(allocs, convey.ShouldEqual, 0)
+	})
+}